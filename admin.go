@@ -0,0 +1,298 @@
+package ktop
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/samuel/go-zookeeper/zk"
+)
+
+// ErrReassignmentInProgress is returned when a partition reassignment is
+// submitted while another one is still in progress
+var ErrReassignmentInProgress = errors.New("a partition reassignment is already in progress")
+
+// ErrElectionInProgress is returned when a preferred replica election is
+// triggered while another one is still in progress
+var ErrElectionInProgress = errors.New("a preferred replica election is already in progress")
+
+// ErrNoController is returned when no broker is registered as the controller,
+// such as during a controller election
+var ErrNoController = errors.New("no controller, an election is in progress")
+
+// zkControllerNode is the registration of the controller in /controller
+type zkControllerNode struct {
+	Version   int    `json:"version"`
+	BrokerID  int32  `json:"brokerid"`
+	Timestamp string `json:"timestamp"`
+}
+
+// Controller is the broker currently acting as the controller of the cluster
+type Controller struct {
+	BrokerID int32
+
+	// time the broker was elected, and the number of elections so far
+	Elected time.Time
+	Epoch   int
+}
+
+// PartitionReplicas is a partition in the admin znodes. Replicas is only set
+// for partition reassignments
+type PartitionReplicas struct {
+	Topic     string  `json:"topic"`
+	Partition int32   `json:"partition"`
+	Replicas  []int32 `json:"replicas,omitempty"`
+}
+
+type PartitionReplicasList []PartitionReplicas
+
+func (pl PartitionReplicasList) Len() int {
+	return len(pl)
+}
+
+func (pl PartitionReplicasList) Swap(i, j int) {
+	pl[i], pl[j] = pl[j], pl[i]
+}
+
+func (pl PartitionReplicasList) Less(i, j int) bool {
+	if pl[i].Topic != pl[j].Topic {
+		return pl[i].Topic < pl[j].Topic
+	}
+	return pl[i].Partition < pl[j].Partition
+}
+
+// zkPartitionsNode is the content of /admin/reassign_partitions and
+// /admin/preferred_replica_election
+type zkPartitionsNode struct {
+	Version    int                   `json:"version"`
+	Partitions PartitionReplicasList `json:"partitions"`
+}
+
+// Controller reads the current controller and its epoch
+func (c *Cluster) Controller() (Controller, error) {
+	if !c.HasZookeeper() {
+		return Controller{}, ErrNoZookeeper
+	}
+
+	data, stat, err := c.zkconn.Get(c.keyBuilder.controller())
+	if err == zk.ErrNoNode {
+		return Controller{}, ErrNoController
+	}
+	if err != nil {
+		return Controller{}, fmt.Errorf("failed to read %s: %v", c.keyBuilder.controller(), err)
+	}
+
+	cn := zkControllerNode{}
+	if err := json.Unmarshal(data, &cn); err != nil {
+		return Controller{}, fmt.Errorf("invalid controller registration: %v", err)
+	}
+
+	controller := Controller{
+		BrokerID: cn.BrokerID,
+		Elected:  msToTime(stat.Ctime),
+	}
+
+	data, _, err = c.zkconn.Get(c.keyBuilder.controllerEpoch())
+	if err != nil && err != zk.ErrNoNode {
+		return controller, fmt.Errorf("failed to read %s: %v", c.keyBuilder.controllerEpoch(), err)
+	}
+	if err == nil {
+		controller.Epoch, _ = strconv.Atoi(strings.TrimSpace(string(data)))
+	}
+
+	return controller, nil
+}
+
+// PendingReassignments returns the partitions being reassigned, with their
+// target replicas. It is empty if no reassignment is in progress
+func (c *Cluster) PendingReassignments() (PartitionReplicasList, error) {
+	return c.readPartitionsNode(c.keyBuilder.reassignPartitions())
+}
+
+// PendingPreferredReplicaElections returns the partitions waiting for a
+// preferred replica election
+func (c *Cluster) PendingPreferredReplicaElections() (PartitionReplicasList, error) {
+	return c.readPartitionsNode(c.keyBuilder.preferredReplicaElection())
+}
+
+// PendingTopicDeletions returns the topics marked for deletion
+func (c *Cluster) PendingTopicDeletions() ([]string, error) {
+	if !c.HasZookeeper() {
+		return nil, ErrNoZookeeper
+	}
+
+	topics, _, err := c.zkconn.Children(c.keyBuilder.deleteTopics())
+	if err == zk.ErrNoNode {
+		return []string{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", c.keyBuilder.deleteTopics(), err)
+	}
+
+	sort.Strings(topics)
+	return topics, nil
+}
+
+func (c *Cluster) readPartitionsNode(path string) (PartitionReplicasList, error) {
+	if !c.HasZookeeper() {
+		return nil, ErrNoZookeeper
+	}
+
+	data, _, err := c.zkconn.Get(path)
+	if err == zk.ErrNoNode {
+		return PartitionReplicasList{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", path, err)
+	}
+
+	pn := zkPartitionsNode{}
+	if err := json.Unmarshal(data, &pn); err != nil {
+		return nil, fmt.Errorf("invalid content in %s: %v", path, err)
+	}
+
+	sort.Sort(pn.Partitions)
+	return pn.Partitions, nil
+}
+
+// ReassignPartitions starts moving the partitions to their replicas, the way
+// kafka-reassign-partitions --execute does
+func (c *Cluster) ReassignPartitions(partitions PartitionReplicasList) error {
+	if !c.HasZookeeper() {
+		return ErrNoZookeeper
+	}
+	if len(partitions) == 0 {
+		return errors.New("no partition to reassign")
+	}
+
+	data, err := json.Marshal(zkPartitionsNode{Version: 1, Partitions: partitions})
+	if err != nil {
+		return err
+	}
+
+	err = c.createNode(c.keyBuilder.reassignPartitions(), data)
+	if err == zk.ErrNodeExists {
+		return ErrReassignmentInProgress
+	}
+	return err
+}
+
+// PreferredReplicaElection asks the controller to move the leadership of the
+// partitions back to their preferred replica, the way
+// kafka-preferred-replica-election does
+func (c *Cluster) PreferredReplicaElection(partitions PartitionReplicasList) error {
+	if !c.HasZookeeper() {
+		return ErrNoZookeeper
+	}
+	if len(partitions) == 0 {
+		return errors.New("no partition to elect a leader for")
+	}
+
+	// only the topic and partition are written, without the replicas
+	elections := PartitionReplicasList{}
+	for _, p := range partitions {
+		elections = append(elections, PartitionReplicas{Topic: p.Topic, Partition: p.Partition})
+	}
+
+	data, err := json.Marshal(zkPartitionsNode{Version: 1, Partitions: elections})
+	if err != nil {
+		return err
+	}
+
+	err = c.createNode(c.keyBuilder.preferredReplicaElection(), data)
+	if err == zk.ErrNodeExists {
+		return ErrElectionInProgress
+	}
+	return err
+}
+
+// DeleteTopic marks the topic for deletion, the way kafka-topics --delete
+// does. The brokers only delete it with delete.topic.enable=true
+func (c *Cluster) DeleteTopic(name string) error {
+	if !c.HasZookeeper() {
+		return ErrNoZookeeper
+	}
+
+	err := c.createNode(c.keyBuilder.deleteTopic(name), []byte{})
+	if err == zk.ErrNodeExists {
+		return errors.New("topic " + name + " is already marked for deletion")
+	}
+	return err
+}
+
+// topicGone returns true once the topic is registered neither in ZooKeeper
+// nor in the metadata of the brokers
+func (c *Cluster) topicGone(name string) bool {
+	if c.HasZookeeper() {
+		exists, _, err := c.zkconn.Exists(c.keyBuilder.topic(name))
+		if err != nil || exists {
+			return false
+		}
+	}
+
+	// all the topics, asking for this one would create it again on brokers
+	// with auto.create.topics.enable
+	metadata, err := fetchMetadata(c.SeedBrokers())
+	if err != nil {
+		return false
+	}
+	for _, t := range metadata.Topics {
+		if t.Name == name {
+			return false
+		}
+	}
+	return true
+}
+
+// watchNode returns a watch fired once when the znode is created, changed or
+// deleted
+func (c *Cluster) watchNode(path string) (<-chan zk.Event, error) {
+	if !c.HasZookeeper() {
+		return nil, ErrNoZookeeper
+	}
+
+	_, _, watch, err := c.zkconn.ExistsW(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to watch %s: %v", path, err)
+	}
+	return watch, nil
+}
+
+// createNode creates a persistent znode, and its missing parents below the
+// top-level znodes of the cluster, such as /admin or /config. The chroot and
+// the top-level znodes are created by the brokers, so if one of them is
+// missing, the cluster is not where it is expected, and nothing is created.
+// It returns zk.ErrNodeExists if the znode already exists
+func (c *Cluster) createNode(path string, data []byte) error {
+	acl := zk.WorldACL(zk.PermAll)
+
+	parts := strings.Split(strings.TrimPrefix(strings.TrimPrefix(path, c.keyBuilder.Chroot), "/"), "/")
+	for i := 1; i < len(parts); i++ {
+		parent := c.keyBuilder.Chroot + "/" + strings.Join(parts[:i], "/")
+		if i == 1 {
+			exists, _, err := c.zkconn.Exists(parent)
+			if err != nil {
+				return fmt.Errorf("failed to read %s: %v", parent, err)
+			}
+			if !exists {
+				return fmt.Errorf("%s does not exist, %s is not the root of a Kafka cluster", parent, c.Root())
+			}
+			continue
+		}
+
+		_, err := c.zkconn.Create(parent, []byte{}, 0, acl)
+		if err != nil && err != zk.ErrNodeExists {
+			return fmt.Errorf("failed to create %s: %v", parent, err)
+		}
+	}
+
+	_, err := c.zkconn.Create(path, data, 0, acl)
+	if err != nil && err != zk.ErrNodeExists {
+		return fmt.Errorf("failed to create %s: %v", path, err)
+	}
+	return err
+}
@@ -0,0 +1,111 @@
+package ktop
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/Shopify/sarama"
+)
+
+// app is a ktop session. It holds the cluster ktop is connected to, and
+// switches between the clusters of the config
+type app struct {
+	config *Config
+
+	current ClusterConfig
+	cluster *Cluster
+	client  sarama.Client
+
+	screen *Screen
+}
+
+// Start runs ktop on the named cluster of the config. The other clusters of
+// the config can be switched to from the cluster picker
+func Start(config *Config, name string) {
+	cc, ok := config.Cluster(name)
+	if !ok {
+		fmt.Println("unknown cluster " + name)
+		return
+	}
+
+	a := &app{config: config}
+	if err := a.connect(cc); err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+	defer a.close()
+
+	a.screen = NewScreen(a.newTopicScreen())
+	a.watch()
+
+	log.Println("showing the topic screen now")
+	a.screen.Show()
+	a.screen.WaitForExit()
+}
+
+// connect opens the cluster and its client, and closes the previous ones
+func (a *app) connect(cc ClusterConfig) error {
+	cluster, err := cc.Connect()
+	if err != nil {
+		return err
+	}
+
+	log.Println("Seedbroker: " + cluster.SeedBroker())
+
+	client, err := sarama.NewClient(cluster.SeedBrokers(), nil)
+	if err != nil {
+		cluster.Close()
+		return err
+	}
+
+	a.close()
+	a.current = cc
+	a.cluster = cluster
+	a.client = client
+	return nil
+}
+
+func (a *app) close() {
+	if a.client != nil {
+		a.client.Close()
+	}
+	if a.cluster != nil {
+		a.cluster.Close()
+	}
+}
+
+// watch updates the screen when brokers or topics of the current cluster
+// change, until the cluster is closed
+func (a *app) watch() {
+	cluster := a.cluster
+	go func() {
+		for {
+			select {
+			case <-cluster.Changes():
+				a.screen.ClusterChanged()
+			case <-cluster.closed:
+				return
+			}
+		}
+	}()
+}
+
+func (a *app) newTopicScreen() *TopicScreen {
+	ts := NewTopicScreen(a.cluster, a.client, a.cluster.SeedBroker())
+	ts.app = a
+	return ts
+}
+
+// switchTo tears down the current cluster and client, connects to another
+// cluster of the config and starts over from its topic list
+func (a *app) switchTo(screen Screen, cc ClusterConfig) error {
+	log.Println("switching to cluster " + cc.Name)
+
+	if err := a.connect(cc); err != nil {
+		return err
+	}
+	a.watch()
+
+	screen.Reset(a.newTopicScreen())
+	return nil
+}
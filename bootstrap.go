@@ -0,0 +1,151 @@
+package ktop
+
+import (
+	"errors"
+	"log"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Shopify/sarama"
+)
+
+// ErrNoZookeeper is returned by the Cluster methods that read ZooKeeper when
+// the cluster was bootstrapped from brokers only
+var ErrNoZookeeper = errors.New("not available without ZooKeeper")
+
+// how often the metadata is polled in the broker-only mode, where there are
+// no ZooKeeper watches
+const metadataPollInterval = time.Second * 30
+
+// NewBrokerCluster builds the view of a cluster from the MetadataRequest
+// responses of its brokers, without ZooKeeper. The bootstrap brokers are
+// tried in order until one of them answers
+func NewBrokerCluster(bootstrap []string) (*Cluster, error) {
+	if len(bootstrap) == 0 {
+		return nil, errors.New("at least one bootstrap broker is required")
+	}
+
+	c := &Cluster{
+		Name:      strings.Join(bootstrap, ","),
+		bootstrap: bootstrap,
+		brokers:   make(map[string]zkBrokerNode),
+
+		watchedBrokers: make(map[string]bool),
+		changes:        make(chan struct{}, 1),
+		closed:         make(chan struct{}),
+	}
+
+	if err := c.refreshMetadata(); err != nil {
+		return nil, err
+	}
+
+	go c.pollMetadata()
+
+	return c, nil
+}
+
+// HasZookeeper returns false if the cluster was bootstrapped from brokers
+// only, in which case the ZooKeeper views are not available
+func (c *Cluster) HasZookeeper() bool {
+	return c.zkconn != nil
+}
+
+// SeedBrokers returns the brokers to bootstrap a client from: the bootstrap
+// brokers, or all the brokers registered in ZooKeeper ordered by ID
+func (c *Cluster) SeedBrokers() []string {
+	if len(c.bootstrap) > 0 {
+		return c.bootstrap
+	}
+
+	seeds := []string{}
+	for _, b := range c.Brokers() {
+		seeds = append(seeds, b.Addr())
+	}
+	return seeds
+}
+
+// fetchMetadata asks the first broker that answers for the metadata of the
+// topics, or of all topics if none is given
+func fetchMetadata(addrs []string, topics ...string) (*sarama.MetadataResponse, error) {
+	var lastErr error = errors.New("no broker to fetch metadata from")
+
+	for _, addr := range addrs {
+		broker := sarama.NewBroker(addr)
+		if err := broker.Open(sarama.NewConfig()); err != nil {
+			lastErr = err
+			continue
+		}
+
+		metadata, err := broker.GetMetadata(&sarama.MetadataRequest{Topics: topics})
+		broker.Close()
+		if err != nil {
+			log.Println("failed to get metadata from " + addr + ": " + err.Error())
+			lastErr = err
+			continue
+		}
+
+		return metadata, nil
+	}
+
+	return nil, lastErr
+}
+
+// refreshMetadata updates the brokers and topic names from the metadata, and
+// signals a change if they differ from the previous view
+func (c *Cluster) refreshMetadata() error {
+	metadata, err := fetchMetadata(c.bootstrap)
+	if err != nil {
+		return err
+	}
+
+	brokers := make(map[string]zkBrokerNode, len(metadata.Brokers))
+	for _, b := range metadata.Brokers {
+		host, port, err := net.SplitHostPort(b.Addr())
+		if err != nil {
+			log.Println("ignore broker with invalid address " + b.Addr())
+			continue
+		}
+		bn := zkBrokerNode{Host: host}
+		bn.Port, _ = strconv.Atoi(port)
+		brokers[strconv.Itoa(int(b.ID()))] = bn
+	}
+
+	names := make([]string, 0, len(metadata.Topics))
+	for _, t := range metadata.Topics {
+		names = append(names, t.Name)
+	}
+	sort.Strings(names)
+
+	c.lock.Lock()
+	changed := len(brokers) != len(c.brokers) || strings.Join(names, ",") != strings.Join(c.topicNames, ",")
+	for ID, bn := range brokers {
+		if old, ok := c.brokers[ID]; !ok || old.Host != bn.Host || old.Port != bn.Port {
+			changed = true
+		}
+	}
+	c.brokers = brokers
+	c.topicNames = names
+	c.lock.Unlock()
+
+	if changed {
+		c.notify()
+	}
+	return nil
+}
+
+// pollMetadata stands in for the ZooKeeper watches in the broker-only mode
+func (c *Cluster) pollMetadata() {
+	for {
+		select {
+		case <-time.After(metadataPollInterval):
+			if err := c.refreshMetadata(); err != nil {
+				log.Println("failed to refresh metadata: " + err.Error())
+			}
+		case <-c.closed:
+			return
+		}
+	}
+}
@@ -0,0 +1,277 @@
+package ktop
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/nsf/termbox-go"
+)
+
+// BrokerScreen lists the live brokers with their registration, their uptime
+// and the number of partitions they lead and replicate
+type BrokerScreen struct {
+	listCursor
+
+	brokers BrokerRegistrations
+
+	// number of partitions each broker leads and replicates
+	leads    map[int32]int
+	replicas map[int32]int
+
+	client  sarama.Client
+	cluster *Cluster
+	broker  string
+}
+
+func NewBrokerScreen(cluster *Cluster, client sarama.Client, broker string) *BrokerScreen {
+	return &BrokerScreen{
+		cluster: cluster,
+		client:  client,
+		broker:  broker,
+	}
+}
+
+func (s *BrokerScreen) WillShow(screen Screen) error {
+	s.brokers = s.cluster.Brokers()
+	s.leads = make(map[int32]int)
+	s.replicas = make(map[int32]int)
+	s.clamp(len(s.brokers))
+
+	metadata, err := fetchMetadata(s.cluster.SeedBrokers())
+	if err != nil {
+		return fmt.Errorf("failed to get the metadata of the cluster: %v", err)
+	}
+
+	for _, t := range metadata.Topics {
+		for _, p := range t.Partitions {
+			if p.Leader >= 0 {
+				s.leads[p.Leader]++
+			}
+			for _, r := range p.Replicas {
+				s.replicas[r]++
+			}
+		}
+	}
+	return nil
+}
+
+func (s *BrokerScreen) Refresh(screen Screen) {
+	termbox.Clear(coldef, coldef)
+	w, h = termbox.Size()
+
+	summary := "Number of Brokers: " + strconv.Itoa(len(s.brokers))
+	screen.Print(summary, 0, 0, coldef, coldef)
+
+	titles := fmt.Sprintf("     %6s %-30s %6s %6s %4s %-20s %10s %6s %8s  %s",
+		"ID", "HOST", "PORT", "JMX", "VER", "REGISTERED", "UPTIME", "LEADS", "REPLICAS", "ENDPOINTS")
+	screen.Print(titles, 0, 2, coldef, coldef)
+
+	first, last := s.visible(len(s.brokers), h-3)
+	for i := first; i < last; i++ {
+		b := s.brokers[i]
+
+		registered, uptime := "-", "-"
+		if t := b.Registered(); !t.IsZero() {
+			registered = t.Format("2006-01-02 15:04:05")
+			uptime = formatDuration(time.Since(t))
+		}
+
+		line := fmt.Sprintf("%6d %-30s %6d %6d %4d %-20s %10s %6d %8d  %s",
+			b.ID, b.Host, b.Port, b.JmxPort, b.Version, registered, uptime,
+			s.leads[b.ID], s.replicas[b.ID], strings.Join(b.Endpoints, ","))
+		screen.Print(line, 5, i-s.Position+3, coldef, coldef)
+	}
+
+	if len(s.brokers) > 0 {
+		screen.Print(" -> ", 0, s.Cursor-s.Position+3, coldef, coldef)
+	}
+
+	termbox.HideCursor()
+	screen.Flush()
+}
+
+// ClusterChanged reloads the live brokers as they join or leave. The number of
+// partitions they lead and replicate is only reloaded with Ctrl-R, as it
+// takes the metadata of every topic
+func (s *BrokerScreen) ClusterChanged(screen Screen) error {
+	s.brokers = s.cluster.Brokers()
+	s.clamp(len(s.brokers))
+	return nil
+}
+
+func (s *BrokerScreen) OnKeyInput(screen Screen, keyEvent termbox.Event) {
+	_, h = termbox.Size()
+
+	switch keyEvent.Type {
+	case termbox.EventKey:
+		switch keyEvent.Key {
+		case termbox.KeyArrowLeft, termbox.KeyCtrlQ:
+			screen.Pop()
+
+		case termbox.KeyEnter, termbox.KeyArrowRight:
+			if len(s.brokers) == 0 {
+				return
+			}
+			screen.Push(NewBrokerPartitionScreen(s.cluster, s.client, s.broker, s.brokers[s.Cursor].ID))
+
+		case termbox.KeyCtrlR:
+			if err := s.WillShow(screen); err != nil {
+				screen.SetError(err)
+			} else {
+				screen.SetStatus("")
+			}
+			s.Refresh(screen)
+
+		default:
+			if s.onKey(keyEvent.Key, len(s.brokers), h-3) {
+				s.Refresh(screen)
+			}
+		}
+	case termbox.EventError:
+		screen.SetError(keyEvent.Err)
+		screen.Flush()
+	}
+}
+
+// BrokerPartitionScreen lists the partitions a broker leads or replicates
+type BrokerPartitionScreen struct {
+	listCursor
+
+	brokerID int32
+	lines    []string
+
+	client  sarama.Client
+	cluster *Cluster
+	broker  string
+}
+
+func NewBrokerPartitionScreen(cluster *Cluster, client sarama.Client, broker string, brokerID int32) *BrokerPartitionScreen {
+	return &BrokerPartitionScreen{
+		cluster:  cluster,
+		client:   client,
+		broker:   broker,
+		brokerID: brokerID,
+	}
+}
+
+func (s *BrokerPartitionScreen) WillShow(screen Screen) error {
+	s.lines = s.lines[:0]
+
+	metadata, err := fetchMetadata(s.cluster.SeedBrokers())
+	if err != nil {
+		return fmt.Errorf("failed to get the metadata of the cluster: %v", err)
+	}
+
+	topics := TopicMetadataList(metadata.Topics)
+	sort.Sort(topics)
+
+	for _, t := range topics {
+		partitions := PartitionMetadata(t.Partitions)
+		sort.Sort(partitions)
+
+		for _, p := range partitions {
+			role := ""
+			switch {
+			case p.Leader == s.brokerID:
+				role = "leader"
+			case containsBrokerID(p.Replicas, s.brokerID) && !containsBrokerID(p.Isr, s.brokerID):
+				role = "out of sync"
+			case containsBrokerID(p.Replicas, s.brokerID):
+				role = "follower"
+			default:
+				continue
+			}
+
+			s.lines = append(s.lines, fmt.Sprintf("%-40s %10d %-12s %8d %20s %20s",
+				t.Name, p.ID, role, p.Leader, formatBrokerIDs(p.Replicas), formatBrokerIDs(p.Isr)))
+		}
+	}
+
+	s.clamp(len(s.lines))
+	return nil
+}
+
+func (s *BrokerPartitionScreen) Refresh(screen Screen) {
+	termbox.Clear(coldef, coldef)
+	w, h = termbox.Size()
+
+	summary := fmt.Sprintf("Broker %d: %s, Number of Partitions: %d", s.brokerID, s.cluster.Broker(strconv.Itoa(int(s.brokerID))), len(s.lines))
+	screen.Print(summary, 0, 0, coldef, coldef)
+
+	titles := fmt.Sprintf("     %-40s %10s %-12s %8s %20s %20s", "TOPIC", "PARTITION", "ROLE", "LEADER", "REPLICAS", "ISR")
+	screen.Print(titles, 0, 2, coldef, coldef)
+
+	first, last := s.visible(len(s.lines), h-3)
+	for i := first; i < last; i++ {
+		screen.Print(s.lines[i], 5, i-s.Position+3, coldef, coldef)
+	}
+
+	if len(s.lines) > 0 {
+		screen.Print(" -> ", 0, s.Cursor-s.Position+3, coldef, coldef)
+	}
+
+	termbox.HideCursor()
+	screen.Flush()
+}
+
+func (s *BrokerPartitionScreen) OnKeyInput(screen Screen, keyEvent termbox.Event) {
+	_, h = termbox.Size()
+
+	switch keyEvent.Type {
+	case termbox.EventKey:
+		switch keyEvent.Key {
+		case termbox.KeyArrowLeft, termbox.KeyCtrlQ:
+			screen.Pop()
+
+		default:
+			if s.onKey(keyEvent.Key, len(s.lines), h-3) {
+				s.Refresh(screen)
+			}
+		}
+	case termbox.EventError:
+		screen.SetError(keyEvent.Err)
+		screen.Flush()
+	}
+}
+
+type TopicMetadataList []*sarama.TopicMetadata
+
+func (tl TopicMetadataList) Len() int {
+	return len(tl)
+}
+
+func (tl TopicMetadataList) Swap(i, j int) {
+	tl[i], tl[j] = tl[j], tl[i]
+}
+
+func (tl TopicMetadataList) Less(i, j int) bool {
+	return tl[i].Name < tl[j].Name
+}
+
+func containsBrokerID(ids []int32, id int32) bool {
+	for _, i := range ids {
+		if i == id {
+			return true
+		}
+	}
+	return false
+}
+
+// formatDuration prints a duration in days, hours and minutes
+func formatDuration(d time.Duration) string {
+	days := int(d.Hours()) / 24
+	hours := int(d.Hours()) % 24
+	minutes := int(d.Minutes()) % 60
+
+	if days > 0 {
+		return fmt.Sprintf("%dd%dh", days, hours)
+	}
+	if hours > 0 {
+		return fmt.Sprintf("%dh%dm", hours, minutes)
+	}
+	return fmt.Sprintf("%dm", minutes)
+}
@@ -5,8 +5,10 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/samuel/go-zookeeper/zk"
@@ -21,85 +23,652 @@ type zkBrokerNode struct {
 	Version   int      `json:"version"`
 }
 
+// zkTopicNode is the replica assignment stored in /brokers/topics/<topic>
+type zkTopicNode struct {
+	Version    int                `json:"version"`
+	Partitions map[string][]int32 `json:"partitions"`
+}
+
+// BrokerRegistration is a broker registered in /brokers/ids
+type BrokerRegistration struct {
+	ID int32
+	zkBrokerNode
+}
+
+// Addr returns the host:port of the broker
+func (b BrokerRegistration) Addr() string {
+	return b.Host + ":" + strconv.Itoa(b.Port)
+}
+
+// Registered returns the time the broker registered itself in ZooKeeper, or
+// the zero time if it is unknown
+func (b BrokerRegistration) Registered() time.Time {
+	ms, err := strconv.ParseInt(b.Timestamp, 10, 64)
+	if err != nil {
+		return time.Time{}
+	}
+	return msToTime(ms)
+}
+
+type BrokerRegistrations []BrokerRegistration
+
+func (br BrokerRegistrations) Len() int {
+	return len(br)
+}
+
+func (br BrokerRegistrations) Swap(i, j int) {
+	br[i], br[j] = br[j], br[i]
+}
+
+func (br BrokerRegistrations) Less(i, j int) bool {
+	return br[i].ID < br[j].ID
+}
+
+// TopicAssignment is the replica assignment of a topic as registered in
+// ZooKeeper, independent of what any live broker reports
+type TopicAssignment struct {
+	Name string
+
+	// partition ID to the list of replica broker IDs. The first replica
+	// is the preferred leader
+	Replicas map[int32][]int32
+
+	// creation and last modification time of the topic znode
+	Ctime time.Time
+	Mtime time.Time
+
+	// version of the topic znode
+	Version int32
+}
+
+// PartitionIDs returns the sorted partition IDs of the topic
+func (t TopicAssignment) PartitionIDs() []int32 {
+	ids := make(PartitionIDs, 0, len(t.Replicas))
+	for id := range t.Replicas {
+		ids = append(ids, id)
+	}
+	sort.Sort(ids)
+	return ids
+}
+
+// PartitionState is the leader and ISR of a partition as written by the
+// controller in the state znode of the partition
+type PartitionState struct {
+	ControllerEpoch int     `json:"controller_epoch"`
+	Leader          int32   `json:"leader"`
+	Version         int     `json:"version"`
+	LeaderEpoch     int     `json:"leader_epoch"`
+	Isr             []int32 `json:"isr"`
+}
+
+type PartitionIDs []int32
+
+func (p PartitionIDs) Len() int {
+	return len(p)
+}
+
+func (p PartitionIDs) Swap(i, j int) {
+	p[i], p[j] = p[j], p[i]
+}
+
+func (p PartitionIDs) Less(i, j int) bool {
+	return p[i] < p[j]
+}
+
+// ConsumerGroup is a high level consumer group registered in /consumers
+type ConsumerGroup struct {
+	Name string
+
+	// topics the group commits offsets for
+	Topics []string
+
+	// ids of the consumer instances currently registered in the group
+	ConsumerIDs []string
+}
+
+// Reads returns true if the group commits offsets for the topic
+func (g ConsumerGroup) Reads(topic string) bool {
+	for _, t := range g.Topics {
+		if t == topic {
+			return true
+		}
+	}
+	return false
+}
+
 type Cluster struct {
 	Name       string
 	zkconn     *zk.Conn
 	brokers    map[string]zkBrokerNode
 	keyBuilder KeyBuilder
+
+	// topic names as last seen by the topic watch
+	topicNames []string
+
+	// broker IDs whose znode is being watched
+	watchedBrokers map[string]bool
+
+	// protects brokers, topicNames and watchedBrokers, which are updated
+	// by the watches
+	lock sync.RWMutex
+
+	// signaled when the watches see brokers or topics change
+	changes chan struct{}
+
+	// closed when the cluster is closed, to stop the watches
+	closed    chan struct{}
+	closeOnce sync.Once
+
+	// bootstrap brokers of a cluster built without ZooKeeper
+	bootstrap []string
+
+	// default values of the topic configs, from the broker server.properties
+	brokerDefaults map[string]string
+}
+
+// ParseZookeeperURL parses a ZooKeeper connection string such as
+// "zk1:2181,zk2:2181,zk3:2181/kafka/prod" into the list of servers and the
+// chroot of the cluster. The chroot is empty if the cluster is registered at
+// the ZooKeeper root. Servers without a port use the default port 2181
+func ParseZookeeperURL(zkstr string) ([]string, string, error) {
+	hosts := zkstr
+	chroot := ""
+	if i := strings.Index(zkstr, "/"); i >= 0 {
+		hosts = zkstr[:i]
+		chroot = strings.TrimRight(zkstr[i:], "/")
+	}
+
+	if strings.Contains(chroot, "//") {
+		return nil, "", fmt.Errorf("Wrong Zookeeper URL %s: empty path element in chroot", zkstr)
+	}
+
+	servers := []string{}
+	for _, server := range strings.Split(hosts, ",") {
+		server = strings.TrimSpace(server)
+		if server == "" {
+			continue
+		}
+		if !strings.Contains(server, ":") {
+			server += ":2181"
+		}
+		servers = append(servers, server)
+	}
+
+	if len(servers) == 0 {
+		return nil, "", errors.New("Wrong Zookeeper URL " + zkstr + ": no server")
+	}
+
+	return servers, chroot, nil
 }
 
 func NewCluster(zkstr string) (*Cluster, error) {
-	urlParts := strings.Split(zkstr, "/")
-	if len(urlParts) != 2 {
-		return nil, errors.New("Wrong Zookeeper URL")
+	servers, chroot, err := ParseZookeeperURL(zkstr)
+	if err != nil {
+		return nil, err
 	}
 
-	conn, _, err := zk.Connect([]string{urlParts[0]}, time.Second*30)
+	conn, _, err := zk.Connect(servers, time.Second*30)
 	if err != nil {
 		return nil, err
 	}
 
+	name := strings.TrimPrefix(chroot, "/")
+	if name == "" {
+		name = strings.Join(servers, ",")
+	}
+
 	c := &Cluster{
 		zkconn:     conn,
-		Name:       urlParts[1],
-		keyBuilder: KeyBuilder{urlParts[1]},
+		Name:       name,
+		keyBuilder: KeyBuilder{Chroot: chroot},
 		brokers:    make(map[string]zkBrokerNode),
+
+		watchedBrokers: make(map[string]bool),
+		changes:        make(chan struct{}, 1),
+		closed:         make(chan struct{}),
 	}
 
-	c.getBrokers()
+	if err := c.getBrokers(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	go c.watchBrokers()
+	go c.watchTopics()
 
 	return c, nil
 }
 
-func (c *Cluster) getBrokers() {
+// Changes is signaled whenever brokers join or leave the cluster, a broker
+// registration changes, or topics are created or deleted
+func (c *Cluster) Changes() <-chan struct{} {
+	return c.changes
+}
+
+func (c *Cluster) notify() {
+	select {
+	case c.changes <- struct{}{}:
+	default:
+		// a change is already pending
+	}
+}
+
+// wait blocks until the watch fires or the cluster is closed. It returns
+// false if the cluster is closed
+func (c *Cluster) wait(watch <-chan zk.Event) bool {
+	select {
+	case ev := <-watch:
+		if ev.Err != nil {
+			log.Println("watch error on " + ev.Path + ": " + ev.Err.Error())
+		}
+		return true
+	case <-c.closed:
+		return false
+	}
+}
+
+// retry waits before setting a watch again after a ZooKeeper error. It
+// returns false if the cluster is closed
+func (c *Cluster) retry() bool {
+	select {
+	case <-time.After(time.Second * 5):
+		return true
+	case <-c.closed:
+		return false
+	}
+}
+
+// watchBrokers watches /brokers/ids, and starts a watch on the znode of each
+// broker that joins the cluster
+func (c *Cluster) watchBrokers() {
+	for {
+		brokerIDs, _, watch, err := c.zkconn.ChildrenW(c.keyBuilder.brokers())
+		if err != nil {
+			log.Println("failed to watch " + c.keyBuilder.brokers() + ": " + err.Error())
+			if !c.retry() {
+				return
+			}
+			continue
+		}
+
+		c.lock.Lock()
+		for _, ID := range brokerIDs {
+			if !c.watchedBrokers[ID] {
+				c.watchedBrokers[ID] = true
+				go c.watchBroker(ID)
+			}
+		}
+		c.lock.Unlock()
+
+		if !c.wait(watch) {
+			return
+		}
+	}
+}
+
+// watchBroker keeps the registration of a broker up to date until the broker
+// leaves the cluster
+func (c *Cluster) watchBroker(ID string) {
+	for {
+		zn, _, watch, err := c.zkconn.GetW(c.keyBuilder.broker(ID))
+		if err == zk.ErrNoNode {
+			log.Println("Broker " + ID + " left the cluster")
+			c.lock.Lock()
+			delete(c.brokers, ID)
+			delete(c.watchedBrokers, ID)
+			c.lock.Unlock()
+			c.notify()
+			return
+		}
+		if err != nil {
+			log.Println("failed to watch " + c.keyBuilder.broker(ID) + ": " + err.Error())
+			if !c.retry() {
+				return
+			}
+			continue
+		}
+
+		bn := zkBrokerNode{}
+		if err := json.Unmarshal(zn, &bn); err != nil {
+			log.Println("invalid registration of broker " + ID + ": " + err.Error())
+		}
+
+		c.lock.Lock()
+		c.brokers[ID] = bn
+		c.lock.Unlock()
+		c.notify()
+
+		if !c.wait(watch) {
+			return
+		}
+	}
+}
+
+// watchTopics keeps the list of topic names up to date
+func (c *Cluster) watchTopics() {
+	for {
+		names, _, watch, err := c.zkconn.ChildrenW(c.keyBuilder.topics())
+		if err != nil {
+			log.Println("failed to watch " + c.keyBuilder.topics() + ": " + err.Error())
+			if !c.retry() {
+				return
+			}
+			continue
+		}
+
+		sort.Strings(names)
+		c.lock.Lock()
+		c.topicNames = names
+		c.lock.Unlock()
+		c.notify()
+
+		if !c.wait(watch) {
+			return
+		}
+	}
+}
+
+// TopicNames returns the names of the topics registered in ZooKeeper, as
+// last seen by the topic watch. It is empty until the watch is set
+func (c *Cluster) TopicNames() []string {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	names := make([]string, len(c.topicNames))
+	copy(names, c.topicNames)
+	return names
+}
+
+func (c *Cluster) getBrokers() error {
 
 	log.Println("finding all broker ids under zookeeper path: " + c.keyBuilder.brokers())
 
 	brokerIDs, _, err := c.zkconn.Children(c.keyBuilder.brokers())
 	if err != nil {
-
-		panic(err)
+		return fmt.Errorf("failed to list the brokers under %s: %v", c.keyBuilder.brokers(), err)
 	}
 
 	for _, ID := range brokerIDs {
 		// get the broker znode
 		zn, _, err := c.zkconn.Get(c.keyBuilder.broker(ID))
+		if err == zk.ErrNoNode {
+			// the broker left after we listed it
+			continue
+		}
 		if err != nil {
-			fmt.Println("### " + c.keyBuilder.broker(ID))
-			panic(err)
+			return fmt.Errorf("failed to read broker %s: %v", c.keyBuilder.broker(ID), err)
 		}
 
 		log.Println("broker znode: " + string(zn))
 
 		bn := zkBrokerNode{}
-		json.Unmarshal([]byte(zn), &bn)
+		if err := json.Unmarshal([]byte(zn), &bn); err != nil {
+			return fmt.Errorf("invalid registration of broker %s: %v", ID, err)
+		}
+		c.lock.Lock()
 		c.brokers[ID] = bn
+		c.lock.Unlock()
 		log.Println("Broker: " + ID + ", Host: " + bn.Host + ", Port: " + strconv.Itoa(bn.Port))
 	}
+
+	return nil
 }
 
 func (c *Cluster) Broker(ID string) string {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
 	return c.brokers[ID].Host + ":" + strconv.Itoa(c.brokers[ID].Port)
 }
 
+// Brokers returns the registrations of the live brokers ordered by ID
+func (c *Cluster) Brokers() BrokerRegistrations {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	brokers := make(BrokerRegistrations, 0, len(c.brokers))
+	for ID, bn := range c.brokers {
+		id, err := strconv.Atoi(ID)
+		if err != nil {
+			continue
+		}
+		brokers = append(brokers, BrokerRegistration{ID: int32(id), zkBrokerNode: bn})
+	}
+	sort.Sort(brokers)
+	return brokers
+}
+
+// SeedBroker returns the first of the SeedBrokers
 func (c *Cluster) SeedBroker() string {
-	for _, bn := range c.brokers {
-		return bn.Host + ":" + strconv.Itoa(bn.Port)
+	seeds := c.SeedBrokers()
+	if len(seeds) == 0 {
+		return ""
+	}
+	return seeds[0]
+}
+
+// Topics reads the replica assignments of all topics registered in ZooKeeper
+func (c *Cluster) Topics() ([]TopicAssignment, error) {
+	if !c.HasZookeeper() {
+		return nil, ErrNoZookeeper
+	}
+
+	names, _, err := c.zkconn.Children(c.keyBuilder.topics())
+	if err != nil {
+		return nil, err
+	}
+
+	topics := make([]TopicAssignment, 0, len(names))
+	for _, name := range names {
+		topic, err := c.Topic(name)
+		if err == zk.ErrNoNode {
+			// the topic was deleted after we listed it
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		topics = append(topics, topic)
+	}
+
+	return topics, nil
+}
+
+// Topic reads the replica assignment of a single topic from ZooKeeper
+func (c *Cluster) Topic(name string) (TopicAssignment, error) {
+	if !c.HasZookeeper() {
+		return TopicAssignment{}, ErrNoZookeeper
+	}
+
+	data, stat, err := c.zkconn.Get(c.keyBuilder.topic(name))
+	if err != nil {
+		return TopicAssignment{}, err
+	}
+
+	tn := zkTopicNode{}
+	if err := json.Unmarshal(data, &tn); err != nil {
+		return TopicAssignment{}, fmt.Errorf("invalid assignment for topic %s: %v", name, err)
+	}
+
+	topic := TopicAssignment{
+		Name:     name,
+		Replicas: make(map[int32][]int32, len(tn.Partitions)),
+		Ctime:    msToTime(stat.Ctime),
+		Mtime:    msToTime(stat.Mtime),
+		Version:  stat.Version,
+	}
+
+	for p, replicas := range tn.Partitions {
+		id, err := strconv.Atoi(p)
+		if err != nil {
+			return TopicAssignment{}, fmt.Errorf("invalid partition %s for topic %s", p, name)
+		}
+		topic.Replicas[int32(id)] = replicas
+	}
+
+	return topic, nil
+}
+
+// PartitionStates reads the state znode of every partition of a topic. A
+// partition without a state, such as one whose leader was never elected, is
+// left out of the result
+func (c *Cluster) PartitionStates(topic string) (map[int32]PartitionState, error) {
+	if !c.HasZookeeper() {
+		return nil, ErrNoZookeeper
+	}
+
+	partitions, _, err := c.zkconn.Children(c.keyBuilder.partitions(topic))
+	if err == zk.ErrNoNode {
+		return map[int32]PartitionState{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	states := make(map[int32]PartitionState, len(partitions))
+	for _, p := range partitions {
+		id, err := strconv.Atoi(p)
+		if err != nil {
+			log.Println("ignore invalid partition " + p + " of topic " + topic)
+			continue
+		}
+
+		data, _, err := c.zkconn.Get(c.keyBuilder.partitionState(topic, p))
+		if err == zk.ErrNoNode {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		state := PartitionState{}
+		if err := json.Unmarshal(data, &state); err != nil {
+			return nil, fmt.Errorf("invalid state for %s:%s: %v", topic, p, err)
+		}
+		states[int32(id)] = state
 	}
 
-	return ""
+	return states, nil
 }
 
-func (c *Cluster) Topics() []string {
-	return []string{}
+// Consumers lists the consumer groups registered in ZooKeeper, with the topics
+// they commit offsets for and their registered consumer instances
+func (c *Cluster) Consumers() ([]ConsumerGroup, error) {
+	if !c.HasZookeeper() {
+		return nil, ErrNoZookeeper
+	}
+
+	names, _, err := c.zkconn.Children(c.keyBuilder.consumers())
+	if err == zk.ErrNoNode {
+		return []ConsumerGroup{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	groups := make([]ConsumerGroup, 0, len(names))
+	for _, name := range names {
+		group, err := c.Consumer(name)
+		if err != nil {
+			return nil, err
+		}
+		groups = append(groups, group)
+	}
+
+	return groups, nil
 }
 
-func (c *Cluster) Consumers() []string {
-	return []string{}
+// Consumer reads a single consumer group from ZooKeeper. A group that has
+// never committed offsets or has no live consumers has empty lists
+func (c *Cluster) Consumer(name string) (ConsumerGroup, error) {
+	if !c.HasZookeeper() {
+		return ConsumerGroup{Name: name}, ErrNoZookeeper
+	}
+
+	group := ConsumerGroup{Name: name}
+
+	topics, _, err := c.zkconn.Children(c.keyBuilder.consumerTopics(name))
+	if err != nil && err != zk.ErrNoNode {
+		return group, err
+	}
+	sort.Strings(topics)
+	group.Topics = topics
+
+	ids, _, err := c.zkconn.Children(c.keyBuilder.consumerIDs(name))
+	if err != nil && err != zk.ErrNoNode {
+		return group, err
+	}
+	sort.Strings(ids)
+	group.ConsumerIDs = ids
+
+	return group, nil
 }
 
-func (c *Cluster) Close() {
-	if c.zkconn != nil {
-		c.zkconn.Close()
+// ConsumerOffsets reads the offsets a consumer group committed to ZooKeeper,
+// keyed by topic and partition
+func (c *Cluster) ConsumerOffsets(group string) (map[string]map[int32]int64, error) {
+	if !c.HasZookeeper() {
+		return nil, ErrNoZookeeper
+	}
+
+	topics, _, err := c.zkconn.Children(c.keyBuilder.consumerTopics(group))
+	if err == zk.ErrNoNode {
+		return map[string]map[int32]int64{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	offsets := make(map[string]map[int32]int64, len(topics))
+	for _, topic := range topics {
+		partitions, _, err := c.zkconn.Children(c.keyBuilder.consumerOffsets(group, topic))
+		if err == zk.ErrNoNode {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		offsets[topic] = make(map[int32]int64, len(partitions))
+		for _, p := range partitions {
+			id, err := strconv.Atoi(p)
+			if err != nil {
+				log.Println("ignore invalid partition " + p + " of topic " + topic + " in consumer group " + group)
+				continue
+			}
+
+			data, _, err := c.zkconn.Get(c.keyBuilder.consumerOffset(group, topic, p))
+			if err == zk.ErrNoNode {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+
+			offset, err := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+			if err != nil {
+				log.Println("ignore invalid offset " + string(data) + " of " + topic + ":" + p + " in consumer group " + group)
+				continue
+			}
+			offsets[topic][int32(id)] = offset
+		}
 	}
+
+	return offsets, nil
+}
+
+func (c *Cluster) Close() {
+	// the cluster switcher may close a cluster already closed
+	c.closeOnce.Do(func() {
+		close(c.closed)
+
+		if c.zkconn != nil {
+			c.zkconn.Close()
+		}
+	})
+}
+
+// msToTime converts the milliseconds since epoch used by ZooKeeper and Kafka
+func msToTime(ms int64) time.Time {
+	return time.Unix(0, ms*int64(time.Millisecond))
 }
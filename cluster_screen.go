@@ -0,0 +1,108 @@
+package ktop
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/nsf/termbox-go"
+)
+
+// ClusterScreen lists the clusters of the config file, to switch to another
+// cluster
+type ClusterScreen struct {
+	listCursor
+
+	app *app
+}
+
+func NewClusterScreen(a *app) *ClusterScreen {
+	return &ClusterScreen{app: a}
+}
+
+func (s *ClusterScreen) WillShow(screen Screen) error {
+	// start from the current cluster
+	for i, cc := range s.app.config.Clusters {
+		if cc.Name == s.app.current.Name {
+			s.Cursor = i
+		}
+	}
+	s.clamp(len(s.app.config.Clusters))
+	return nil
+}
+
+func (s *ClusterScreen) Refresh(screen Screen) {
+	termbox.Clear(coldef, coldef)
+	w, h = termbox.Size()
+
+	summary := "Number of Clusters: " + strconv.Itoa(len(s.app.config.Clusters)) + ", current: " + s.app.current.Name
+	screen.Print(summary, 0, 0, coldef, coldef)
+
+	titles := fmt.Sprintf("     %-24s %s", "CLUSTER", "CONNECTION")
+	screen.Print(titles, 0, 2, coldef, coldef)
+
+	clusters := s.app.config.Clusters
+	first, last := s.visible(len(clusters), h-3)
+	for i := first; i < last; i++ {
+		cc := clusters[i]
+
+		connection := cc.Zookeeper
+		if connection == "" {
+			connection = "brokers " + strings.Join(cc.Brokers, ",")
+		}
+
+		fg := coldef
+		if cc.Name == s.app.current.Name {
+			fg = termbox.ColorGreen
+		}
+
+		line := fmt.Sprintf("%-24s %s", cc.Name, connection)
+		screen.Print(line, 5, i-s.Position+3, fg, coldef)
+	}
+
+	if len(clusters) > 0 {
+		screen.Print(" -> ", 0, s.Cursor-s.Position+3, coldef, coldef)
+	}
+
+	termbox.HideCursor()
+	screen.Flush()
+}
+
+func (s *ClusterScreen) OnKeyInput(screen Screen, keyEvent termbox.Event) {
+	_, h = termbox.Size()
+
+	switch keyEvent.Type {
+	case termbox.EventKey:
+		switch keyEvent.Key {
+		case termbox.KeyArrowLeft, termbox.KeyCtrlQ:
+			screen.Pop()
+
+		case termbox.KeyEnter, termbox.KeyArrowRight:
+			clusters := s.app.config.Clusters
+			if len(clusters) == 0 {
+				return
+			}
+
+			cc := clusters[s.Cursor]
+			if cc.Name == s.app.current.Name {
+				screen.Pop()
+				return
+			}
+
+			screen.SetStatus("connecting to " + cc.Name + "...")
+			screen.Flush()
+			if err := s.app.switchTo(screen, cc); err != nil {
+				screen.SetError(fmt.Errorf("failed to connect to %s: %v", cc.Name, err))
+				s.Refresh(screen)
+			}
+
+		default:
+			if s.onKey(keyEvent.Key, len(s.app.config.Clusters), h-3) {
+				s.Refresh(screen)
+			}
+		}
+	case termbox.EventError:
+		screen.SetError(keyEvent.Err)
+		screen.Flush()
+	}
+}
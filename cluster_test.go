@@ -0,0 +1,45 @@
+package ktop
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseZookeeperURL(t *testing.T) {
+	tests := []struct {
+		url     string
+		servers []string
+		chroot  string
+	}{
+		{"zk1:2181", []string{"zk1:2181"}, ""},
+		{"zk1:2181,zk2:2182,zk3:2183", []string{"zk1:2181", "zk2:2182", "zk3:2183"}, ""},
+		{"zk1,zk2:2182", []string{"zk1:2181", "zk2:2182"}, ""},
+		{"zk1:2181, zk2:2181 ,", []string{"zk1:2181", "zk2:2181"}, ""},
+		{"zk1:2181/kafka", []string{"zk1:2181"}, "/kafka"},
+		{"zk1:2181,zk2:2181/kafka/us-east/prod", []string{"zk1:2181", "zk2:2181"}, "/kafka/us-east/prod"},
+		{"zk1:2181/kafka/prod/", []string{"zk1:2181"}, "/kafka/prod"},
+		{"zk1:2181/", []string{"zk1:2181"}, ""},
+	}
+
+	for _, test := range tests {
+		servers, chroot, err := ParseZookeeperURL(test.url)
+		if err != nil {
+			t.Errorf("%s: %v", test.url, err)
+			continue
+		}
+		if !reflect.DeepEqual(servers, test.servers) {
+			t.Errorf("%s: servers %v, expected %v", test.url, servers, test.servers)
+		}
+		if chroot != test.chroot {
+			t.Errorf("%s: chroot %q, expected %q", test.url, chroot, test.chroot)
+		}
+	}
+}
+
+func TestParseZookeeperURLErrors(t *testing.T) {
+	for _, url := range []string{"", "/kafka", ",/kafka", "zk1:2181/kafka//prod"} {
+		if _, _, err := ParseZookeeperURL(url); err == nil {
+			t.Errorf("%q: expected an error", url)
+		}
+	}
+}
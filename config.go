@@ -0,0 +1,122 @@
+package ktop
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// ClusterConfig is a named cluster in the config file. A cluster is reached
+// either through ZooKeeper, or through bootstrap brokers only
+type ClusterConfig struct {
+	Name string `json:"name"`
+
+	// ZooKeeper connection string, with the chroot of the cluster
+	Zookeeper string `json:"zookeeper,omitempty"`
+
+	// bootstrap brokers, used when Zookeeper is not set
+	Brokers []string `json:"brokers,omitempty"`
+
+	// server.properties of the brokers, to compare the topic config
+	// overrides with
+	BrokerDefaults string `json:"brokerDefaults,omitempty"`
+}
+
+// Config is the content of the config file, ~/.ktop.json by default:
+//
+//	{
+//	  "default": "prod",
+//	  "clusters": [
+//	    {"name": "prod", "zookeeper": "zk1:2181,zk2:2181/kafka/prod", "brokerDefaults": "/etc/kafka/server.properties"},
+//	    {"name": "edge", "brokers": ["edge1:9092", "edge2:9092"]}
+//	  ]
+//	}
+type Config struct {
+	// name of the cluster to start with
+	Default string `json:"default,omitempty"`
+
+	Clusters []ClusterConfig `json:"clusters"`
+}
+
+// DefaultConfigFile returns the path of the config file in the home directory
+func DefaultConfigFile() string {
+	return filepath.Join(os.Getenv("HOME"), ".ktop.json")
+}
+
+// LoadConfig reads the config file. A missing file is an empty config
+func LoadConfig(path string) (*Config, error) {
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return &Config{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	config := &Config{}
+	if err := json.Unmarshal(data, config); err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %v", path, err)
+	}
+
+	names := make(map[string]bool)
+	for _, cc := range config.Clusters {
+		if cc.Name == "" {
+			return nil, fmt.Errorf("invalid config file %s: a cluster has no name", path)
+		}
+		if names[cc.Name] {
+			return nil, fmt.Errorf("invalid config file %s: cluster %s is defined twice", path, cc.Name)
+		}
+		if cc.Zookeeper == "" && len(cc.Brokers) == 0 {
+			return nil, fmt.Errorf("invalid config file %s: cluster %s has neither zookeeper nor brokers", path, cc.Name)
+		}
+		names[cc.Name] = true
+	}
+
+	return config, nil
+}
+
+// Cluster returns the cluster with the given name
+func (c *Config) Cluster(name string) (ClusterConfig, bool) {
+	for _, cc := range c.Clusters {
+		if cc.Name == name {
+			return cc, true
+		}
+	}
+	return ClusterConfig{}, false
+}
+
+// Connect builds the Cluster and loads its per-cluster settings
+func (cc ClusterConfig) Connect() (*Cluster, error) {
+	var cluster *Cluster
+	var err error
+
+	switch {
+	case cc.Zookeeper != "":
+		cluster, err = NewCluster(cc.Zookeeper)
+	case len(cc.Brokers) > 0:
+		cluster, err = NewBrokerCluster(cc.Brokers)
+	default:
+		err = errors.New("cluster " + cc.Name + " has neither zookeeper nor brokers")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if cc.Name != "" {
+		cluster.Name = cc.Name
+	}
+
+	if cc.BrokerDefaults != "" {
+		defaults, err := LoadBrokerDefaults(cc.BrokerDefaults)
+		if err != nil {
+			cluster.Close()
+			return nil, err
+		}
+		cluster.brokerDefaults = defaults
+	}
+
+	return cluster, nil
+}
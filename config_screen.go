@@ -0,0 +1,250 @@
+package ktop
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/nsf/termbox-go"
+)
+
+// ConfigScreen edits the config overrides of a topic. The edits are kept in a
+// draft, and only written after reviewing them
+type ConfigScreen struct {
+	listCursor
+
+	topic string
+
+	// overrides as read from ZooKeeper, and the version of their znode
+	config  map[string]string
+	version int32
+
+	// overrides being edited, nil until loaded
+	draft map[string]string
+
+	// the topic-level configs, then the unknown keys the topic overrides
+	keys []string
+
+	// set after warning about unsaved changes, leaving again discards them
+	leaving bool
+
+	cluster *Cluster
+}
+
+func NewConfigScreen(cluster *Cluster, topic string) *ConfigScreen {
+	return &ConfigScreen{
+		cluster: cluster,
+		topic:   topic,
+	}
+}
+
+func (s *ConfigScreen) WillShow(screen Screen) error {
+	// keep the draft when coming back from editing a value
+	if s.draft != nil {
+		return nil
+	}
+
+	config, version, err := s.cluster.topicConfigVersion(s.topic)
+	if err != nil {
+		return fmt.Errorf("failed to read the config of topic %s: %v", s.topic, err)
+	}
+	s.config, s.version = config, version
+
+	s.draft = make(map[string]string)
+	for key, value := range config {
+		s.draft[key] = value
+	}
+
+	s.keys = []string{}
+	for _, key := range TopicConfigKeys {
+		s.keys = append(s.keys, key.Name)
+	}
+	unknown := []string{}
+	for key := range config {
+		if _, ok := topicConfigKey(key); !ok {
+			unknown = append(unknown, key)
+		}
+	}
+	sort.Strings(unknown)
+	s.keys = append(s.keys, unknown...)
+
+	s.leaving = false
+	s.clamp(len(s.keys))
+	return nil
+}
+
+// changes lists the differences between the overrides and the draft
+func (s *ConfigScreen) changes() []string {
+	keys := []string{}
+	for key := range s.config {
+		keys = append(keys, key)
+	}
+	for key := range s.draft {
+		if _, ok := s.config[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	lines := []string{}
+	for _, key := range keys {
+		before, hadBefore := s.config[key]
+		after, hasAfter := s.draft[key]
+		switch {
+		case !hadBefore:
+			lines = append(lines, fmt.Sprintf("+ %-32s %s", key, after))
+		case !hasAfter:
+			lines = append(lines, fmt.Sprintf("- %-32s %s", key, before))
+		case before != after:
+			lines = append(lines, fmt.Sprintf("~ %-32s %s -> %s", key, before, after))
+		}
+	}
+	return lines
+}
+
+func (s *ConfigScreen) Refresh(screen Screen) {
+	termbox.Clear(coldef, coldef)
+	w, h = termbox.Size()
+
+	summary := fmt.Sprintf("Config overrides of topic %s: %d, unsaved changes: %d (Enter to edit, Delete to remove, Ctrl-S to save, Ctrl-R to discard)",
+		s.topic, len(s.draft), len(s.changes()))
+	screen.Print(summary, 0, 0, coldef, coldef)
+
+	titles := fmt.Sprintf("     %-32s %-24s %-24s %s", "KEY", "OVERRIDE", "BROKER DEFAULT", "TYPE")
+	screen.Print(titles, 0, 2, coldef, coldef)
+
+	first, last := s.visible(len(s.keys), h-4)
+	for i := first; i < last; i++ {
+		key := s.keys[i]
+
+		kind := "unknown"
+		if k, ok := topicConfigKey(key); ok {
+			kind = k.Type.String()
+		}
+
+		fg := coldef
+		if _, ok := s.draft[key]; ok {
+			fg = termbox.ColorGreen
+		}
+		before, hadBefore := s.config[key]
+		after, hasAfter := s.draft[key]
+		if hadBefore != hasAfter || before != after {
+			fg = termbox.ColorYellow
+		}
+
+		line := fmt.Sprintf("%-32s %-24s %-24s %s", key, after, s.cluster.brokerDefaults[key], kind)
+		screen.Print(line, 5, i-s.Position+3, fg, coldef)
+	}
+
+	if len(s.keys) > 0 {
+		screen.Print(" -> ", 0, s.Cursor-s.Position+3, coldef, coldef)
+	}
+
+	termbox.HideCursor()
+	screen.Flush()
+}
+
+func (s *ConfigScreen) OnKeyInput(screen Screen, keyEvent termbox.Event) {
+	_, h = termbox.Size()
+
+	switch keyEvent.Type {
+	case termbox.EventKey:
+		switch keyEvent.Key {
+		case termbox.KeyArrowLeft, termbox.KeyCtrlQ:
+			if len(s.changes()) > 0 && !s.leaving {
+				s.leaving = true
+				screen.SetStatus("unsaved changes, Ctrl-S to save them or leave again to discard them")
+				s.Refresh(screen)
+				return
+			}
+			screen.Pop()
+
+		case termbox.KeyEnter, termbox.KeyArrowRight:
+			if len(s.keys) > 0 {
+				s.edit(screen, s.keys[s.Cursor])
+			}
+
+		case termbox.KeyDelete:
+			if len(s.keys) > 0 {
+				delete(s.draft, s.keys[s.Cursor])
+				s.Refresh(screen)
+			}
+
+		case termbox.KeyCtrlS:
+			s.save(screen)
+
+		case termbox.KeyCtrlR:
+			s.draft = nil
+			if err := s.WillShow(screen); err != nil {
+				screen.SetError(err)
+			} else {
+				screen.SetStatus("")
+			}
+			s.Refresh(screen)
+
+		default:
+			if s.onKey(keyEvent.Key, len(s.keys), h-4) {
+				s.Refresh(screen)
+			}
+		}
+	case termbox.EventError:
+		screen.SetError(keyEvent.Err)
+		screen.Flush()
+	}
+}
+
+// edit asks for the new value of a key. An empty value removes the override
+func (s *ConfigScreen) edit(screen Screen, key string) {
+	kind := "unknown config, the override can only be removed"
+	if k, ok := topicConfigKey(key); ok {
+		kind = k.Type.String()
+	}
+
+	lines := []string{
+		"Override:       " + s.config[key],
+		"Broker default: " + s.cluster.brokerDefaults[key],
+		"Type:           " + kind,
+		"",
+		"An empty value removes the override.",
+	}
+
+	prompt := NewPromptScreen("Edit "+key+" of topic "+s.topic, lines, key+" = ", func(screen Screen, input string) error {
+		if input == "" {
+			delete(s.draft, key)
+		} else {
+			if err := ValidateTopicConfig(key, input); err != nil {
+				return err
+			}
+			s.draft[key] = input
+		}
+		s.leaving = false
+
+		screen.Pop()
+		return nil
+	})
+	prompt.Input = s.draft[key]
+	screen.Push(prompt)
+}
+
+// save shows the changes, and writes the overrides after confirmation
+func (s *ConfigScreen) save(screen Screen) {
+	changes := s.changes()
+	if len(changes) == 0 {
+		screen.SetStatus("no change to save")
+		s.Refresh(screen)
+		return
+	}
+
+	title := fmt.Sprintf("Save %d config changes of topic %s", len(changes), s.topic)
+	screen.Push(NewConfirmScreen(title, changes, "save", func(screen Screen) error {
+		if err := s.cluster.SetTopicConfig(s.topic, s.draft, s.version); err != nil {
+			return err
+		}
+
+		// read the config back
+		s.draft = nil
+		screen.Pop()
+		screen.SetStatus("config of topic " + s.topic + " saved, the brokers are notified")
+		screen.Flush()
+		return nil
+	}))
+}
@@ -0,0 +1,170 @@
+package ktop
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Shopify/sarama"
+	"github.com/nsf/termbox-go"
+)
+
+type ConsumerGroupList []ConsumerGroup
+
+func (cl ConsumerGroupList) Len() int {
+	return len(cl)
+}
+
+func (cl ConsumerGroupList) Swap(i, j int) {
+	cl[i], cl[j] = cl[j], cl[i]
+}
+
+func (cl ConsumerGroupList) Less(i, j int) bool {
+	return strings.ToLower(cl[i].Name) < strings.ToLower(cl[j].Name)
+}
+
+// ConsumerScreen lists the consumer groups registered in ZooKeeper. If topic is
+// set, only the groups committing offsets for that topic are listed
+type ConsumerScreen struct {
+	listCursor
+
+	topic string
+
+	// all groups of the topic, and the groups whose name contains Query
+	allGroups ConsumerGroupList
+	groups    ConsumerGroupList
+
+	// query string. Groups committing offsets to Kafka are not registered
+	// in ZooKeeper, so a query matching no group can be opened by name
+	Query string
+
+	client  sarama.Client
+	cluster *Cluster
+	broker  string
+}
+
+func NewConsumerScreen(cluster *Cluster, client sarama.Client, broker string, topic string) *ConsumerScreen {
+	return &ConsumerScreen{
+		cluster: cluster,
+		client:  client,
+		broker:  broker,
+		topic:   topic,
+	}
+}
+
+func (s *ConsumerScreen) WillShow(screen Screen) error {
+	groups, err := s.cluster.Consumers()
+	if err != nil && err != ErrNoZookeeper {
+		return fmt.Errorf("failed to read consumer groups: %v", err)
+	}
+
+	s.allGroups = s.allGroups[:0]
+	for _, g := range groups {
+		if s.topic == "" || g.Reads(s.topic) {
+			s.allGroups = append(s.allGroups, g)
+		}
+	}
+	sort.Sort(s.allGroups)
+	s.filter()
+	return nil
+}
+
+func (s *ConsumerScreen) filter() {
+	s.groups = ConsumerGroupList{}
+	for _, g := range s.allGroups {
+		if strings.Contains(strings.ToLower(g.Name), strings.ToLower(s.Query)) {
+			s.groups = append(s.groups, g)
+		}
+	}
+	s.clamp(len(s.groups))
+}
+
+func (s *ConsumerScreen) Refresh(screen Screen) {
+	termbox.Clear(coldef, coldef)
+	w, h = termbox.Size()
+
+	summary := "Number of Consumer Groups: " + strconv.Itoa(len(s.groups))
+	if s.topic != "" {
+		summary += ", reading topic " + s.topic
+	}
+	screen.Print(summary, 0, 0, coldef, coldef)
+	if !s.cluster.HasZookeeper() && s.Query == "" {
+		screen.Print("Consumer groups are registered in ZooKeeper. Type a group name to look up its Kafka offsets", 0, 1, termbox.ColorBlue, coldef)
+	} else if len(s.groups) == 0 && s.Query != "" {
+		screen.Print(s.Query+" (enter to look up the group in Kafka)", 0, 1, termbox.ColorBlue, coldef)
+	} else {
+		screen.Print(s.Query, 0, 1, termbox.ColorBlue, coldef)
+	}
+
+	widthForGroup := strconv.Itoa(w/2 - 5)
+	titles := fmt.Sprintf("     %-"+widthForGroup+"s %10s  %s", "GROUP", "CONSUMERS", "TOPICS")
+	screen.Print(titles, 0, 2, coldef, coldef)
+
+	first, last := s.visible(len(s.groups), h-3)
+	for i := first; i < last; i++ {
+		g := s.groups[i]
+		line := fmt.Sprintf("%-"+widthForGroup+"s %10d  %s", g.Name, len(g.ConsumerIDs), strings.Join(g.Topics, ","))
+		screen.Print(line, 5, i-s.Position+3, coldef, coldef)
+	}
+
+	if len(s.groups) > 0 {
+		screen.Print(" -> ", 0, s.Cursor-s.Position+3, coldef, coldef)
+	}
+
+	termbox.HideCursor()
+	screen.Flush()
+}
+
+func (s *ConsumerScreen) OnKeyInput(screen Screen, keyEvent termbox.Event) {
+	_, h = termbox.Size()
+
+	switch keyEvent.Type {
+	case termbox.EventKey:
+		switch keyEvent.Key {
+		case termbox.KeyArrowLeft, termbox.KeyCtrlQ:
+			screen.Pop()
+
+		case termbox.KeyEnter, termbox.KeyArrowRight:
+			if len(s.groups) > 0 {
+				screen.Push(NewLagScreen(s.cluster, s.client, s.groups[s.Cursor].Name))
+			} else if s.Query != "" {
+				screen.Push(NewLagScreen(s.cluster, s.client, s.Query))
+			}
+
+		case termbox.KeyCtrlR:
+			if err := s.WillShow(screen); err != nil {
+				screen.SetError(err)
+			} else {
+				screen.SetStatus("")
+			}
+			s.Refresh(screen)
+
+		case termbox.KeyBackspace, termbox.KeyBackspace2:
+			if len(s.Query) > 0 {
+				s.Query = s.Query[0 : len(s.Query)-1]
+			}
+			s.filter()
+			s.Refresh(screen)
+
+		default:
+			if s.onKey(keyEvent.Key, len(s.groups), h-3) {
+				s.Refresh(screen)
+				return
+			}
+
+			// group names commonly use '-', '_' and '.' as well
+			if keyEvent.Ch >= 'a' && keyEvent.Ch <= 'z' ||
+				keyEvent.Ch >= 'A' && keyEvent.Ch <= 'Z' ||
+				keyEvent.Ch >= '0' && keyEvent.Ch <= '9' ||
+				keyEvent.Ch == '-' || keyEvent.Ch == '_' || keyEvent.Ch == '.' {
+				s.Query += string(keyEvent.Ch)
+				s.filter()
+				s.Refresh(screen)
+			}
+		}
+	case termbox.EventError:
+		screen.SetError(keyEvent.Err)
+		screen.Flush()
+	}
+}
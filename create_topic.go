@@ -0,0 +1,173 @@
+package ktop
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// defaultReplicationFactor is proposed for new topics, when there are enough
+// live brokers
+const defaultReplicationFactor = 3
+
+// topicDraft is a topic being defined in the create-topic wizard
+type topicDraft struct {
+	cluster *Cluster
+	brokers []int32
+
+	// existing topics, to check the name against
+	topics []string
+
+	name              string
+	partitions        int
+	replicationFactor int
+	config            map[string]string
+	assignment        map[int32][]int32
+}
+
+// createTopic asks for the name, the number of partitions, the replication
+// factor and the config overrides of a new topic, one after the other. It
+// previews the replica assignment on the live brokers and creates the topic
+// after confirmation
+func createTopic(screen Screen, cluster *Cluster, topics []string) error {
+	if !cluster.HasZookeeper() {
+		return ErrNoZookeeper
+	}
+
+	d := &topicDraft{cluster: cluster, topics: topics}
+	for _, b := range cluster.Brokers() {
+		d.brokers = append(d.brokers, b.ID)
+	}
+	if len(d.brokers) == 0 {
+		return errors.New("no live broker to assign the partitions to")
+	}
+
+	screen.Push(NewPromptScreen(d.title(), d.lines(), "name: ", d.onName))
+	return nil
+}
+
+func (d *topicDraft) title() string {
+	return "Create a topic in " + d.cluster.Name
+}
+
+// lines summarizes the topic so far
+func (d *topicDraft) lines() []string {
+	lines := []string{
+		"Live brokers: " + formatBrokerIDs(d.brokers),
+		"",
+	}
+	if d.name == "" {
+		return append(lines, fmt.Sprintf("Topic names have at most %d letters, digits, '.', '_' or '-'.", maxTopicNameLength))
+	}
+
+	lines = append(lines, "Name:               "+d.name)
+	if d.partitions > 0 {
+		lines = append(lines, "Partitions:         "+strconv.Itoa(d.partitions))
+	}
+	if d.replicationFactor > 0 {
+		lines = append(lines, "Replication factor: "+strconv.Itoa(d.replicationFactor))
+	}
+	if d.config != nil {
+		keys := []string{}
+		for key := range d.config {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		lines = append(lines, fmt.Sprintf("Config overrides:   %d", len(keys)))
+		for _, key := range keys {
+			lines = append(lines, fmt.Sprintf("    %-32s %s", key, d.config[key]))
+		}
+	}
+
+	if collisions := topicCollisions(d.name, d.topics); len(collisions) > 0 {
+		lines = append(lines, "",
+			"WARNING: the name only differs by '.' or '_' from "+strings.Join(collisions, ", ")+",",
+			"their metric names collide.")
+	}
+	return lines
+}
+
+func (d *topicDraft) onName(screen Screen, input string) error {
+	name := strings.TrimSpace(input)
+	if err := ValidateTopicName(name); err != nil {
+		return err
+	}
+	for _, t := range d.topics {
+		if t == name {
+			return errors.New("topic " + name + " already exists")
+		}
+	}
+
+	d.name = name
+	screen.Replace(NewPromptScreen(d.title(), d.lines(), "number of partitions: ", d.onPartitions))
+	return nil
+}
+
+func (d *topicDraft) onPartitions(screen Screen, input string) error {
+	partitions, err := strconv.Atoi(strings.TrimSpace(input))
+	if err != nil || partitions <= 0 {
+		return errors.New("the number of partitions must be a positive number")
+	}
+
+	d.partitions = partitions
+	prompt := fmt.Sprintf("replication factor [%d]: ", d.defaultReplicationFactor())
+	screen.Replace(NewPromptScreen(d.title(), d.lines(), prompt, d.onReplicationFactor))
+	return nil
+}
+
+func (d *topicDraft) defaultReplicationFactor() int {
+	if len(d.brokers) < defaultReplicationFactor {
+		return len(d.brokers)
+	}
+	return defaultReplicationFactor
+}
+
+func (d *topicDraft) onReplicationFactor(screen Screen, input string) error {
+	replicationFactor := d.defaultReplicationFactor()
+	if strings.TrimSpace(input) != "" {
+		var err error
+		replicationFactor, err = strconv.Atoi(strings.TrimSpace(input))
+		if err != nil || replicationFactor <= 0 {
+			return errors.New("the replication factor must be a positive number")
+		}
+	}
+	if replicationFactor > len(d.brokers) {
+		return fmt.Errorf("replication factor %d is larger than the %d live brokers", replicationFactor, len(d.brokers))
+	}
+
+	d.replicationFactor = replicationFactor
+	screen.Replace(NewPromptScreen(d.title(), d.lines(), "config overrides, as key=value,key=value: ", d.onConfig))
+	return nil
+}
+
+func (d *topicDraft) onConfig(screen Screen, input string) error {
+	config, err := ParseConfigOverrides(input)
+	if err != nil {
+		return err
+	}
+	d.config = config
+
+	d.assignment, err = assignReplicas(d.brokers, 0, d.partitions, d.replicationFactor, -1, -1)
+	if err != nil {
+		return err
+	}
+
+	lines := append(d.lines(), "", "Replica assignment:")
+	lines = append(lines, assignmentLines(d.assignment)...)
+	screen.Replace(NewConfirmScreen(d.title(), lines, "create", d.create))
+	return nil
+}
+
+func (d *topicDraft) create(screen Screen) error {
+	if err := d.cluster.CreateTopic(d.name, d.assignment, d.config); err != nil {
+		return err
+	}
+
+	screen.Pop()
+	screen.SetStatus("topic " + d.name + " created")
+	screen.Flush()
+	return nil
+}
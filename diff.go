@@ -0,0 +1,272 @@
+package ktop
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"time"
+)
+
+// SnapshotDiff is what changed in a cluster between two snapshots
+type SnapshotDiff struct {
+	From      string    `json:"from"`
+	FromTaken time.Time `json:"from_taken"`
+	To        string    `json:"to"`
+	ToTaken   time.Time `json:"to_taken"`
+
+	BrokersAdded   []int32 `json:"brokers_added"`
+	BrokersRemoved []int32 `json:"brokers_removed"`
+
+	TopicsCreated []string `json:"topics_created"`
+	TopicsDeleted []string `json:"topics_deleted"`
+
+	PartitionCounts []PartitionCountChange `json:"partition_counts"`
+	ReplicaMoves    []ReplicasChange       `json:"replica_moves"`
+	LeaderChanges   []LeaderChange         `json:"leader_changes"`
+	IsrChanges      []ReplicasChange       `json:"isr_changes"`
+	ConfigChanges   []ConfigValueChange    `json:"config_changes"`
+}
+
+// PartitionCountChange is a topic whose number of partitions changed
+type PartitionCountChange struct {
+	Topic string `json:"topic"`
+	From  int    `json:"from"`
+	To    int    `json:"to"`
+}
+
+// ReplicasChange is a partition whose replicas or ISR changed
+type ReplicasChange struct {
+	Topic     string  `json:"topic"`
+	Partition int32   `json:"partition"`
+	From      []int32 `json:"from"`
+	To        []int32 `json:"to"`
+}
+
+// LeaderChange is a partition whose leader changed
+type LeaderChange struct {
+	Topic     string `json:"topic"`
+	Partition int32  `json:"partition"`
+	From      int32  `json:"from"`
+	To        int32  `json:"to"`
+}
+
+// ConfigValueChange is a config override of a topic that was added, changed
+// or removed. From is empty for an added override, To for a removed one
+type ConfigValueChange struct {
+	Topic string `json:"topic"`
+	Key   string `json:"key"`
+	From  string `json:"from,omitempty"`
+	To    string `json:"to,omitempty"`
+}
+
+// DiffSnapshots compares two snapshots of a cluster. Partitions of topics
+// created or deleted in between are not reported one by one
+func DiffSnapshots(from *Snapshot, to *Snapshot) *SnapshotDiff {
+	diff := &SnapshotDiff{
+		From:            from.Cluster,
+		FromTaken:       from.Taken,
+		To:              to.Cluster,
+		ToTaken:         to.Taken,
+		BrokersAdded:    []int32{},
+		BrokersRemoved:  []int32{},
+		TopicsCreated:   []string{},
+		TopicsDeleted:   []string{},
+		PartitionCounts: []PartitionCountChange{},
+		ReplicaMoves:    []ReplicasChange{},
+		LeaderChanges:   []LeaderChange{},
+		IsrChanges:      []ReplicasChange{},
+		ConfigChanges:   []ConfigValueChange{},
+	}
+
+	fromBrokers := make(map[int32]bool)
+	for _, b := range from.Brokers {
+		fromBrokers[b.ID] = true
+	}
+	toBrokers := make(map[int32]bool)
+	for _, b := range to.Brokers {
+		toBrokers[b.ID] = true
+		if !fromBrokers[b.ID] {
+			diff.BrokersAdded = append(diff.BrokersAdded, b.ID)
+		}
+	}
+	for _, b := range from.Brokers {
+		if !toBrokers[b.ID] {
+			diff.BrokersRemoved = append(diff.BrokersRemoved, b.ID)
+		}
+	}
+
+	fromTopics := make(map[string]SnapshotTopic)
+	for _, t := range from.Topics {
+		fromTopics[t.Name] = t
+	}
+	toTopics := make(map[string]bool)
+	for _, t := range to.Topics {
+		toTopics[t.Name] = true
+
+		before, ok := fromTopics[t.Name]
+		if !ok {
+			diff.TopicsCreated = append(diff.TopicsCreated, t.Name)
+			continue
+		}
+		diff.diffTopic(before, t)
+	}
+	for _, t := range from.Topics {
+		if !toTopics[t.Name] {
+			diff.TopicsDeleted = append(diff.TopicsDeleted, t.Name)
+		}
+	}
+
+	return diff
+}
+
+func (d *SnapshotDiff) diffTopic(from SnapshotTopic, to SnapshotTopic) {
+	if len(from.Partitions) != len(to.Partitions) {
+		d.PartitionCounts = append(d.PartitionCounts, PartitionCountChange{
+			Topic: to.Name,
+			From:  len(from.Partitions),
+			To:    len(to.Partitions),
+		})
+	}
+
+	partitions := make(map[int32]SnapshotPartition)
+	for _, p := range from.Partitions {
+		partitions[p.ID] = p
+	}
+	for _, p := range to.Partitions {
+		before, ok := partitions[p.ID]
+		if !ok {
+			continue
+		}
+
+		if !sameBrokerIDs(before.Replicas, p.Replicas) {
+			d.ReplicaMoves = append(d.ReplicaMoves, ReplicasChange{to.Name, p.ID, before.Replicas, p.Replicas})
+		}
+		if before.Leader != p.Leader {
+			d.LeaderChanges = append(d.LeaderChanges, LeaderChange{to.Name, p.ID, before.Leader, p.Leader})
+		}
+		if !sameBrokerIDSet(before.Isr, p.Isr) {
+			d.IsrChanges = append(d.IsrChanges, ReplicasChange{to.Name, p.ID, before.Isr, p.Isr})
+		}
+	}
+
+	keys := []string{}
+	for key := range from.Config {
+		keys = append(keys, key)
+	}
+	for key := range to.Config {
+		if _, ok := from.Config[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if from.Config[key] != to.Config[key] {
+			d.ConfigChanges = append(d.ConfigChanges, ConfigValueChange{to.Name, key, from.Config[key], to.Config[key]})
+		}
+	}
+}
+
+// Empty returns true if nothing changed
+func (d *SnapshotDiff) Empty() bool {
+	return len(d.BrokersAdded) == 0 && len(d.BrokersRemoved) == 0 &&
+		len(d.TopicsCreated) == 0 && len(d.TopicsDeleted) == 0 &&
+		len(d.PartitionCounts) == 0 && len(d.ReplicaMoves) == 0 &&
+		len(d.LeaderChanges) == 0 && len(d.IsrChanges) == 0 &&
+		len(d.ConfigChanges) == 0
+}
+
+// Lines formats the diff as text, one section per kind of change. Sections
+// without changes are left out
+func (d *SnapshotDiff) Lines() []string {
+	lines := []string{
+		fmt.Sprintf("from %s taken %s", d.From, d.FromTaken.Format(time.RFC3339)),
+		fmt.Sprintf("to   %s taken %s", d.To, d.ToTaken.Format(time.RFC3339)),
+	}
+	if d.Empty() {
+		return append(lines, "", "no changes")
+	}
+
+	section := func(title string, count int) {
+		if count > 0 {
+			lines = append(lines, "", fmt.Sprintf("%s (%d)", title, count))
+		}
+	}
+
+	section("brokers added", len(d.BrokersAdded))
+	for _, id := range d.BrokersAdded {
+		lines = append(lines, fmt.Sprintf("    + %d", id))
+	}
+	section("brokers removed", len(d.BrokersRemoved))
+	for _, id := range d.BrokersRemoved {
+		lines = append(lines, fmt.Sprintf("    - %d", id))
+	}
+
+	section("topics created", len(d.TopicsCreated))
+	for _, name := range d.TopicsCreated {
+		lines = append(lines, "    + "+name)
+	}
+	section("topics deleted", len(d.TopicsDeleted))
+	for _, name := range d.TopicsDeleted {
+		lines = append(lines, "    - "+name)
+	}
+
+	section("partition counts", len(d.PartitionCounts))
+	for _, c := range d.PartitionCounts {
+		lines = append(lines, fmt.Sprintf("    %-40s %d -> %d", c.Topic, c.From, c.To))
+	}
+
+	section("replica moves", len(d.ReplicaMoves))
+	for _, c := range d.ReplicaMoves {
+		lines = append(lines, fmt.Sprintf("    %-40s %-16s -> %s",
+			fmt.Sprintf("%s:%d", c.Topic, c.Partition), formatBrokerIDs(c.From), formatBrokerIDs(c.To)))
+	}
+
+	section("leader changes", len(d.LeaderChanges))
+	for _, c := range d.LeaderChanges {
+		lines = append(lines, fmt.Sprintf("    %-40s %d -> %d",
+			fmt.Sprintf("%s:%d", c.Topic, c.Partition), c.From, c.To))
+	}
+
+	section("ISR changes", len(d.IsrChanges))
+	for _, c := range d.IsrChanges {
+		lines = append(lines, fmt.Sprintf("    %-40s %-16s -> %s",
+			fmt.Sprintf("%s:%d", c.Topic, c.Partition), formatBrokerIDs(c.From), formatBrokerIDs(c.To)))
+	}
+
+	section("config changes", len(d.ConfigChanges))
+	for _, c := range d.ConfigChanges {
+		from, to := c.From, c.To
+		if from == "" {
+			from = "(default)"
+		}
+		if to == "" {
+			to = "(default)"
+		}
+		lines = append(lines, fmt.Sprintf("    %-40s %s: %s -> %s", c.Topic, c.Key, from, to))
+	}
+
+	return lines
+}
+
+// LatestSnapshotFile returns the most recent snapshot of the cluster written
+// with the default file name in the current directory, or an empty string
+func LatestSnapshotFile(cluster string) string {
+	files, err := filepath.Glob("ktop-snapshot-*.json")
+	if err != nil {
+		return ""
+	}
+
+	// the glob also matches the clusters whose name starts with this one,
+	// only keep the exact name followed by the time
+	name := regexp.MustCompile("^ktop-snapshot-" + regexp.QuoteMeta(snapshotFileCluster(cluster)) + `-\d{8}-\d{6}\.json$`)
+	latest := ""
+	for _, file := range files {
+		// the time in the file name sorts chronologically
+		if name.MatchString(filepath.Base(file)) && file > latest {
+			latest = file
+		}
+	}
+	return latest
+}
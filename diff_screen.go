@@ -0,0 +1,173 @@
+package ktop
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/Shopify/sarama"
+	"github.com/nsf/termbox-go"
+)
+
+// DiffScreen shows what changed in the cluster since its latest snapshot in
+// the current directory
+type DiffScreen struct {
+	listCursor
+
+	path string
+	from *Snapshot
+	diff *SnapshotDiff
+
+	lines []string
+
+	// live snapshot, taken in the background as it reads every topic
+	lock    sync.Mutex
+	to      *Snapshot
+	toErr   error
+	taking  bool
+	stopped bool
+
+	// the differences are not known until the live snapshot is taken
+	loading bool
+
+	client  sarama.Client
+	cluster *Cluster
+}
+
+func NewDiffScreen(cluster *Cluster, client sarama.Client) *DiffScreen {
+	return &DiffScreen{
+		cluster: cluster,
+		client:  client,
+	}
+}
+
+func (s *DiffScreen) WillShow(screen Screen) error {
+	if s.from == nil {
+		s.path = LatestSnapshotFile(s.cluster.Name)
+		if s.path == "" {
+			return errors.New("no snapshot of " + s.cluster.Name + " in the current directory, use Ctrl-P on the topic list to take one")
+		}
+
+		from, err := ReadSnapshot(s.path)
+		if err != nil {
+			return err
+		}
+		s.from = from
+	}
+
+	s.lock.Lock()
+	to, err := s.to, s.toErr
+	if to == nil && err == nil && !s.taking {
+		s.taking = true
+		s.takeSnapshot(screen)
+	}
+	s.loading = to == nil && err == nil
+	s.lock.Unlock()
+
+	if err != nil {
+		return err
+	}
+	if to == nil {
+		s.diff = nil
+		s.lines = nil
+		s.clamp(0)
+		return nil
+	}
+
+	s.diff = DiffSnapshots(s.from, to)
+	s.lines = s.diff.Lines()
+	s.clamp(len(s.lines))
+	return nil
+}
+
+// takeSnapshot reads the cluster in the background, and reloads the screen
+// with the differences once it is done
+func (s *DiffScreen) takeSnapshot(screen Screen) {
+	cluster, client := s.cluster, s.client
+	go func() {
+		to, err := TakeSnapshot(cluster, client, false)
+
+		s.lock.Lock()
+		s.to, s.toErr = to, err
+		s.taking = false
+		stopped := s.stopped
+		s.lock.Unlock()
+
+		// the screen was closed while taking the snapshot
+		if !stopped {
+			screen.Update()
+		}
+	}()
+}
+
+func (s *DiffScreen) Refresh(screen Screen) {
+	termbox.Clear(coldef, coldef)
+	w, h = termbox.Size()
+
+	summary := "Changes since snapshot " + s.path + " (Ctrl-R to compare again)"
+	screen.Print(summary, 0, 0, coldef, coldef)
+
+	if s.loading {
+		screen.Print("taking a snapshot of "+s.cluster.Name+"...", 5, 2, coldef, coldef)
+	}
+
+	first, last := s.visible(len(s.lines), h-3)
+	for i := first; i < last; i++ {
+		line := s.lines[i]
+
+		fg := coldef
+		switch {
+		case strings.HasPrefix(line, "    + "):
+			fg = termbox.ColorGreen
+		case strings.HasPrefix(line, "    - "):
+			fg = termbox.ColorRed
+		}
+		screen.Print(line, 5, i-s.Position+2, fg, coldef)
+	}
+
+	if len(s.lines) > 0 {
+		screen.Print(" -> ", 0, s.Cursor-s.Position+2, coldef, coldef)
+	}
+
+	termbox.HideCursor()
+	screen.Flush()
+}
+
+func (s *DiffScreen) OnKeyInput(screen Screen, keyEvent termbox.Event) {
+	_, h = termbox.Size()
+
+	switch keyEvent.Type {
+	case termbox.EventKey:
+		switch keyEvent.Key {
+		case termbox.KeyArrowLeft, termbox.KeyCtrlQ:
+			s.lock.Lock()
+			s.stopped = true
+			s.lock.Unlock()
+			screen.Pop()
+
+		case termbox.KeyCtrlR:
+			// take a new snapshot, unless one is still being taken
+			s.lock.Lock()
+			if !s.taking {
+				s.to, s.toErr = nil, nil
+			}
+			s.lock.Unlock()
+
+			if err := s.WillShow(screen); err != nil {
+				screen.SetError(fmt.Errorf("failed to compare with the snapshot: %v", err))
+			} else {
+				screen.SetStatus("")
+			}
+			s.Refresh(screen)
+
+		default:
+			if s.onKey(keyEvent.Key, len(s.lines), h-3) {
+				s.Refresh(screen)
+			}
+		}
+	case termbox.EventError:
+		screen.SetError(keyEvent.Err)
+		screen.Flush()
+	}
+}
@@ -0,0 +1,182 @@
+package ktop
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/nsf/termbox-go"
+)
+
+const (
+	// how long to wait for the leaders to move to their preferred replica
+	electionTimeout = time.Minute
+
+	// how often the leaders are read again while waiting
+	electionPollInterval = 2 * time.Second
+)
+
+// ElectionScreen follows a preferred replica election until the leader of each
+// partition is its preferred replica, or until electionTimeout
+type ElectionScreen struct {
+	listCursor
+
+	// partitions of the election, with their replicas. The preferred
+	// replica is the first one
+	partitions PartitionReplicasList
+	leaders    map[string]int32
+	moved      int
+
+	started  time.Time
+	timedOut bool
+
+	// closed to stop polling, when leaving the screen or once done
+	stop    chan struct{}
+	stopped bool
+
+	cluster *Cluster
+}
+
+func NewElectionScreen(cluster *Cluster, partitions PartitionReplicasList) *ElectionScreen {
+	return &ElectionScreen{
+		cluster:    cluster,
+		partitions: partitions,
+		started:    time.Now(),
+	}
+}
+
+func (s *ElectionScreen) WillShow(screen Screen) error {
+	if s.stop == nil {
+		s.stop = make(chan struct{})
+		go s.poll(screen)
+	}
+
+	topics := []string{}
+	for i, p := range s.partitions {
+		if i == 0 || s.partitions[i-1].Topic != p.Topic {
+			topics = append(topics, p.Topic)
+		}
+	}
+
+	metadata, err := fetchMetadata(s.cluster.SeedBrokers(), topics...)
+	if err != nil {
+		return fmt.Errorf("failed to get the metadata of the topics: %v", err)
+	}
+
+	s.leaders = make(map[string]int32)
+	for _, t := range metadata.Topics {
+		for _, p := range t.Partitions {
+			s.leaders[fmt.Sprintf("%s:%d", t.Name, p.ID)] = p.Leader
+		}
+	}
+
+	s.moved = 0
+	for _, p := range s.partitions {
+		if s.ledByPreferred(p) {
+			s.moved++
+		}
+	}
+
+	switch {
+	case s.moved == len(s.partitions):
+		s.stopPolling()
+	case time.Since(s.started) > electionTimeout:
+		s.timedOut = true
+		s.stopPolling()
+	}
+
+	s.clamp(len(s.partitions))
+	return nil
+}
+
+// ledByPreferred returns true if the partition is led by its preferred
+// replica
+func (s *ElectionScreen) ledByPreferred(p PartitionReplicas) bool {
+	leader, ok := s.leaders[fmt.Sprintf("%s:%d", p.Topic, p.Partition)]
+	return ok && len(p.Replicas) > 0 && leader == p.Replicas[0]
+}
+
+func (s *ElectionScreen) poll(screen Screen) {
+	for {
+		select {
+		case <-time.After(electionPollInterval):
+		case <-s.stop:
+			return
+		case <-s.cluster.closed:
+			return
+		}
+
+		screen.Update()
+	}
+}
+
+func (s *ElectionScreen) stopPolling() {
+	if !s.stopped {
+		s.stopped = true
+		close(s.stop)
+	}
+}
+
+func (s *ElectionScreen) Refresh(screen Screen) {
+	termbox.Clear(coldef, coldef)
+	w, h = termbox.Size()
+
+	summary := fmt.Sprintf("Preferred replica election of %d partitions: %d moved, %d waiting",
+		len(s.partitions), s.moved, len(s.partitions)-s.moved)
+	if s.timedOut {
+		summary += fmt.Sprintf(", gave up after %v", electionTimeout)
+	}
+	screen.Print(summary, 0, 0, coldef, coldef)
+
+	titles := fmt.Sprintf("     %-40s %-10s %-10s %s", "PARTITION", "PREFERRED", "LEADER", "STATE")
+	screen.Print(titles, 0, 2, coldef, coldef)
+
+	first, last := s.visible(len(s.partitions), h-4)
+	for i := first; i < last; i++ {
+		p := s.partitions[i]
+
+		leader := "-"
+		if id, ok := s.leaders[fmt.Sprintf("%s:%d", p.Topic, p.Partition)]; ok {
+			leader = fmt.Sprintf("%d", id)
+		}
+
+		state, fg := "waiting", termbox.ColorYellow
+		switch {
+		case s.ledByPreferred(p):
+			state, fg = "moved", termbox.ColorGreen
+		case s.timedOut:
+			state, fg = "not moved", termbox.ColorRed
+		}
+
+		line := fmt.Sprintf("%-40s %-10d %-10s %s",
+			fmt.Sprintf("%s:%d", p.Topic, p.Partition), p.Replicas[0], leader, state)
+		screen.Print(line, 5, i-s.Position+3, fg, coldef)
+	}
+
+	if len(s.partitions) > 0 {
+		screen.Print(" -> ", 0, s.Cursor-s.Position+3, coldef, coldef)
+	}
+
+	termbox.HideCursor()
+	screen.Flush()
+}
+
+func (s *ElectionScreen) OnKeyInput(screen Screen, keyEvent termbox.Event) {
+	_, h = termbox.Size()
+
+	switch keyEvent.Type {
+	case termbox.EventKey:
+		switch keyEvent.Key {
+		case termbox.KeyArrowLeft, termbox.KeyCtrlQ:
+			s.stopPolling()
+			screen.Pop()
+
+		default:
+			if s.onKey(keyEvent.Key, len(s.partitions), h-4) {
+				s.Refresh(screen)
+			}
+		}
+	case termbox.EventError:
+		screen.SetError(keyEvent.Err)
+		screen.Flush()
+	}
+}
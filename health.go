@@ -0,0 +1,141 @@
+package ktop
+
+import (
+	"sort"
+
+	"github.com/Shopify/sarama"
+)
+
+// UnhealthyPartition is a partition that is offline or under-replicated
+type UnhealthyPartition struct {
+	Topic     string
+	Partition int32
+	Leader    int32
+	Replicas  []int32
+	Isr       []int32
+
+	// true if the partition has no leader
+	Offline bool
+}
+
+// MissingReplicas returns the replicas that are not in the ISR
+func (p UnhealthyPartition) MissingReplicas() []int32 {
+	missing := []int32{}
+	for _, r := range p.Replicas {
+		if !containsBrokerID(p.Isr, r) {
+			missing = append(missing, r)
+		}
+	}
+	return missing
+}
+
+type UnhealthyPartitions []UnhealthyPartition
+
+func (up UnhealthyPartitions) Len() int {
+	return len(up)
+}
+
+func (up UnhealthyPartitions) Swap(i, j int) {
+	up[i], up[j] = up[j], up[i]
+}
+
+func (up UnhealthyPartitions) Less(i, j int) bool {
+	if up[i].Topic != up[j].Topic {
+		return up[i].Topic < up[j].Topic
+	}
+	return up[i].Partition < up[j].Partition
+}
+
+// ClusterHealth lists the offline and under-replicated partitions of the
+// cluster
+type ClusterHealth struct {
+	// partitions without a leader
+	Offline UnhealthyPartitions
+
+	// partitions whose ISR is smaller than the replica list, or with a
+	// replica on a dead broker. Offline partitions are not repeated here
+	UnderReplicated UnhealthyPartitions
+}
+
+// checkHealth scans the metadata of all topics for partitions without a
+// leader, and partitions whose ISR is smaller than the replica list. The
+// brokers leave the dead brokers out of both the replicas and the ISR, and
+// flag the partition with ErrReplicaNotAvailable: it is under-replicated too
+func checkHealth(metadata *sarama.MetadataResponse) ClusterHealth {
+	health := ClusterHealth{
+		Offline:         UnhealthyPartitions{},
+		UnderReplicated: UnhealthyPartitions{},
+	}
+
+	for _, t := range metadata.Topics {
+		for _, p := range t.Partitions {
+			up := UnhealthyPartition{
+				Topic:     t.Name,
+				Partition: p.ID,
+				Leader:    p.Leader,
+				Replicas:  p.Replicas,
+				Isr:       p.Isr,
+			}
+
+			switch {
+			case p.Leader < 0 || p.Err == sarama.ErrLeaderNotAvailable:
+				up.Offline = true
+				health.Offline = append(health.Offline, up)
+			case len(p.Isr) < len(p.Replicas) || p.Err == sarama.ErrReplicaNotAvailable:
+				health.UnderReplicated = append(health.UnderReplicated, up)
+			}
+		}
+	}
+
+	sort.Sort(health.Offline)
+	sort.Sort(health.UnderReplicated)
+	return health
+}
+
+// Topics returns the topics with unhealthy partitions
+func (h ClusterHealth) Topics() []string {
+	seen := make(map[string]bool)
+	topics := []string{}
+	for _, list := range []UnhealthyPartitions{h.Offline, h.UnderReplicated} {
+		for _, p := range list {
+			if !seen[p.Topic] {
+				seen[p.Topic] = true
+				topics = append(topics, p.Topic)
+			}
+		}
+	}
+	sort.Strings(topics)
+	return topics
+}
+
+// setAssignments replaces the replicas of the unhealthy partitions with their
+// assignment, by topic and partition, such as registered in ZooKeeper. Unlike
+// the metadata, the assignment still has the dead brokers
+func (h ClusterHealth) setAssignments(assignments map[string]map[int32][]int32) {
+	for _, list := range []UnhealthyPartitions{h.Offline, h.UnderReplicated} {
+		for i := range list {
+			if replicas, ok := assignments[list[i].Topic][list[i].Partition]; ok {
+				list[i].Replicas = replicas
+			}
+		}
+	}
+}
+
+// ByMissingBroker groups the unhealthy partitions by the replicas missing
+// from their ISR. The partitions whose missing replica is unknown, because
+// the metadata leaves the dead brokers out, are grouped under -1
+func (h ClusterHealth) ByMissingBroker() map[int32]UnhealthyPartitions {
+	byBroker := make(map[int32]UnhealthyPartitions)
+	for _, list := range []UnhealthyPartitions{h.Offline, h.UnderReplicated} {
+		for _, p := range list {
+			missing := p.MissingReplicas()
+			if len(missing) == 0 {
+				byBroker[-1] = append(byBroker[-1], p)
+			}
+			for _, r := range missing {
+				byBroker[r] = append(byBroker[r], p)
+			}
+		}
+	}
+	return byBroker
+}
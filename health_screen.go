@@ -0,0 +1,173 @@
+package ktop
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+
+	"github.com/Shopify/sarama"
+	"github.com/nsf/termbox-go"
+)
+
+// HealthScreen lists the offline and under-replicated partitions of the whole
+// cluster, grouped by topic or by the broker missing from the ISR
+type HealthScreen struct {
+	listCursor
+
+	health ClusterHealth
+
+	// group by the missing broker instead of by topic
+	byBroker bool
+
+	lines []string
+
+	client  sarama.Client
+	cluster *Cluster
+	broker  string
+}
+
+func NewHealthScreen(cluster *Cluster, client sarama.Client, broker string) *HealthScreen {
+	return &HealthScreen{
+		cluster: cluster,
+		client:  client,
+		broker:  broker,
+	}
+}
+
+func (s *HealthScreen) WillShow(screen Screen) error {
+	metadata, err := fetchMetadata(s.cluster.SeedBrokers())
+	if err != nil {
+		return fmt.Errorf("failed to get the metadata of the cluster: %v", err)
+	}
+
+	s.health = checkHealth(metadata)
+
+	// the metadata leaves the dead brokers out of the replicas, the
+	// assignment in ZooKeeper tells which brokers are missing
+	if s.cluster.HasZookeeper() {
+		assignments := make(map[string]map[int32][]int32)
+		for _, topic := range s.health.Topics() {
+			assignment, err := s.cluster.Topic(topic)
+			if err != nil {
+				log.Println("failed to read the assignment of topic " + topic + ": " + err.Error())
+				continue
+			}
+			assignments[topic] = assignment.Replicas
+		}
+		s.health.setAssignments(assignments)
+	}
+
+	s.format()
+	return nil
+}
+
+func (s *HealthScreen) format() {
+	s.lines = []string{}
+
+	if s.byBroker {
+		byBroker := s.health.ByMissingBroker()
+		ids := make(PartitionIDs, 0, len(byBroker))
+		for id := range byBroker {
+			ids = append(ids, id)
+		}
+		sort.Sort(ids)
+
+		for _, id := range ids {
+			if id == -1 {
+				s.lines = append(s.lines, fmt.Sprintf("unknown broker, not in the metadata: missing from %d partitions", len(byBroker[id])))
+			} else {
+				s.lines = append(s.lines, fmt.Sprintf("broker %d (%s): missing from %d partitions",
+					id, s.cluster.Broker(strconv.Itoa(int(id))), len(byBroker[id])))
+			}
+			for _, p := range byBroker[id] {
+				s.lines = append(s.lines, "    "+formatUnhealthyPartition(p))
+			}
+		}
+	} else {
+		s.lines = append(s.lines, s.byTopic(s.health.Offline)...)
+		s.lines = append(s.lines, s.byTopic(s.health.UnderReplicated)...)
+	}
+
+	s.clamp(len(s.lines))
+}
+
+func (s *HealthScreen) byTopic(partitions UnhealthyPartitions) []string {
+	lines := []string{}
+	for i, p := range partitions {
+		if i == 0 || partitions[i-1].Topic != p.Topic {
+			lines = append(lines, p.Topic)
+		}
+		lines = append(lines, "    "+formatUnhealthyPartition(p))
+	}
+	return lines
+}
+
+func formatUnhealthyPartition(p UnhealthyPartition) string {
+	state := "under-replicated"
+	if p.Offline {
+		state = "offline"
+	}
+	return fmt.Sprintf("%-40s %-17s leader: %-4d replicas: %-16s isr: %-16s missing: %s",
+		fmt.Sprintf("%s:%d", p.Topic, p.Partition), state, p.Leader,
+		formatBrokerIDs(p.Replicas), formatBrokerIDs(p.Isr), formatBrokerIDs(p.MissingReplicas()))
+}
+
+func (s *HealthScreen) Refresh(screen Screen) {
+	termbox.Clear(coldef, coldef)
+	w, h = termbox.Size()
+
+	grouping := "topic"
+	if s.byBroker {
+		grouping = "missing broker"
+	}
+	summary := fmt.Sprintf("Offline Partitions: %d, Under-replicated Partitions: %d, grouped by %s (tab to switch)",
+		len(s.health.Offline), len(s.health.UnderReplicated), grouping)
+	screen.Print(summary, 0, 0, coldef, coldef)
+
+	first, last := s.visible(len(s.lines), h-3)
+	for i := first; i < last; i++ {
+		screen.Print(s.lines[i], 5, i-s.Position+2, coldef, coldef)
+	}
+
+	if len(s.lines) > 0 {
+		screen.Print(" -> ", 0, s.Cursor-s.Position+2, coldef, coldef)
+	}
+
+	termbox.HideCursor()
+	screen.Flush()
+}
+
+func (s *HealthScreen) OnKeyInput(screen Screen, keyEvent termbox.Event) {
+	_, h = termbox.Size()
+
+	switch keyEvent.Type {
+	case termbox.EventKey:
+		switch keyEvent.Key {
+		case termbox.KeyArrowLeft, termbox.KeyCtrlQ:
+			screen.Pop()
+
+		case termbox.KeyTab:
+			s.byBroker = !s.byBroker
+			s.Position, s.Cursor = 0, 0
+			s.format()
+			s.Refresh(screen)
+
+		case termbox.KeyCtrlR:
+			if err := s.WillShow(screen); err != nil {
+				screen.SetError(err)
+			} else {
+				screen.SetStatus("")
+			}
+			s.Refresh(screen)
+
+		default:
+			if s.onKey(keyEvent.Key, len(s.lines), h-3) {
+				s.Refresh(screen)
+			}
+		}
+	case termbox.EventError:
+		screen.SetError(keyEvent.Err)
+		screen.Flush()
+	}
+}
@@ -0,0 +1,71 @@
+package ktop
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/Shopify/sarama"
+)
+
+// deadBrokerMetadata is the metadata of a cluster where broker 3 is dead. The
+// brokers leave it out of the replicas and the ISR of its partitions, and
+// flag them with ErrReplicaNotAvailable
+func deadBrokerMetadata() *sarama.MetadataResponse {
+	return &sarama.MetadataResponse{
+		Topics: []*sarama.TopicMetadata{
+			{
+				Name: "orders",
+				Partitions: []*sarama.PartitionMetadata{
+					{ID: 0, Leader: 1, Replicas: []int32{1, 2}, Isr: []int32{1, 2}, Err: sarama.ErrReplicaNotAvailable},
+					{ID: 1, Leader: 2, Replicas: []int32{2, 1}, Isr: []int32{2, 1}},
+					{ID: 2, Leader: -1, Replicas: []int32{}, Isr: []int32{}, Err: sarama.ErrLeaderNotAvailable},
+				},
+			},
+			{
+				Name: "payments",
+				Partitions: []*sarama.PartitionMetadata{
+					{ID: 0, Leader: 2, Replicas: []int32{2, 1}, Isr: []int32{2}},
+					{ID: 1, Leader: 1, Replicas: []int32{1}, Isr: []int32{1}, Err: sarama.ErrReplicaNotAvailable},
+				},
+			},
+		},
+	}
+}
+
+func TestCheckHealthDeadBroker(t *testing.T) {
+	health := checkHealth(deadBrokerMetadata())
+
+	if len(health.Offline) != 1 || health.Offline[0].Topic != "orders" || health.Offline[0].Partition != 2 {
+		t.Errorf("offline partitions %v, expected orders:2", health.Offline)
+	}
+
+	under := []string{}
+	for _, p := range health.UnderReplicated {
+		under = append(under, fmt.Sprintf("%s:%d", p.Topic, p.Partition))
+	}
+	if expected := []string{"orders:0", "payments:0", "payments:1"}; !reflect.DeepEqual(under, expected) {
+		t.Errorf("under-replicated partitions %v, expected %v", under, expected)
+	}
+
+	// without the assignment, the dead broker is unknown
+	byBroker := health.ByMissingBroker()
+	if len(byBroker[-1]) != 3 {
+		t.Errorf("%d partitions with an unknown missing broker, expected 3", len(byBroker[-1]))
+	}
+
+	health.setAssignments(map[string]map[int32][]int32{
+		"orders":   {0: {1, 2, 3}, 1: {2, 1, 3}, 2: {3, 1, 2}},
+		"payments": {0: {2, 1}, 1: {1, 3}},
+	})
+	byBroker = health.ByMissingBroker()
+	if len(byBroker[-1]) != 0 {
+		t.Errorf("%d partitions with an unknown missing broker, expected none", len(byBroker[-1]))
+	}
+	if len(byBroker[3]) != 3 {
+		t.Errorf("broker 3 missing from %d partitions, expected 3: %v", len(byBroker[3]), byBroker[3])
+	}
+	if len(byBroker[1]) != 2 {
+		t.Errorf("broker 1 missing from %d partitions, expected 2: %v", len(byBroker[1]), byBroker[1])
+	}
+}
@@ -2,81 +2,104 @@ package ktop
 
 import "fmt"
 
+// KeyBuilder builds the ZooKeeper paths of a Kafka cluster
 type KeyBuilder struct {
-	ClusterID string
+	// chroot of the cluster, such as "/kafka/prod". It is empty if the
+	// cluster is registered at the ZooKeeper root
+	Chroot string
 }
 
 func (k *KeyBuilder) cluster() string {
-	return fmt.Sprintf("/%s", k.ClusterID)
+	if k.Chroot == "" {
+		return "/"
+	}
+	return k.Chroot
 }
 
 func (k *KeyBuilder) brokers() string {
-	if k.ClusterID == "" {
-		return "/brokers/ids"
-	}
-
-	return fmt.Sprintf("/%s/brokers/ids", k.ClusterID)
+	return k.Chroot + "/brokers/ids"
 }
 
 func (k *KeyBuilder) broker(id string) string {
-	if k.ClusterID == "" {
-		return fmt.Sprintf("/brokers/ids/%s", id)
-	}
-	return fmt.Sprintf("/%s/brokers/ids/%s", k.ClusterID, id)
+	return fmt.Sprintf("%s/brokers/ids/%s", k.Chroot, id)
 }
 
 func (k *KeyBuilder) topics() string {
-	if k.ClusterID == "" {
-		return "/brokers/topics"
-	}
-	return fmt.Sprintf("/%s/brokers/topics", k.ClusterID)
+	return k.Chroot + "/brokers/topics"
 }
 
 func (k *KeyBuilder) topic(name string) string {
-	if k.ClusterID == "" {
-		return fmt.Sprintf("/brokers/topics/%s", name)
-	}
-	return fmt.Sprintf("/%s/brokers/topics/%s", k.ClusterID, name)
+	return fmt.Sprintf("%s/brokers/topics/%s", k.Chroot, name)
 }
 
 func (k *KeyBuilder) partitions(topic string) string {
-	if k.ClusterID == "" {
-		return fmt.Sprintf("/brokers/topics/%s/partitions", topic)
-	}
-	return fmt.Sprintf("/%s/brokers/topics/%s/partitions", k.ClusterID, topic)
+	return fmt.Sprintf("%s/brokers/topics/%s/partitions", k.Chroot, topic)
 }
 
 func (k *KeyBuilder) partition(topic string, partitionID string) string {
-	if k.ClusterID == "" {
-		return fmt.Sprintf("/brokers/topics/%s/partitions/%s", topic, partitionID)
-	}
-	return fmt.Sprintf("/%s/brokers/topics/%s/partitions/%s", k.ClusterID, topic, partitionID)
+	return fmt.Sprintf("%s/brokers/topics/%s/partitions/%s", k.Chroot, topic, partitionID)
 }
 
 func (k *KeyBuilder) partitionState(topic string, partitionID string) string {
-	if k.ClusterID == "" {
-		return fmt.Sprintf("brokers/topics/%s/partitions/%s/state", topic, partitionID)
-	}
-	return fmt.Sprintf("/%s/brokers/topics/%s/partitions/%s/state", k.ClusterID, topic, partitionID)
+	return fmt.Sprintf("%s/brokers/topics/%s/partitions/%s/state", k.Chroot, topic, partitionID)
 }
 
 func (k *KeyBuilder) consumers() string {
-	if k.ClusterID == "" {
-		return "/consumers"
-	}
-	return fmt.Sprintf("/%s/consumers", k.ClusterID)
+	return k.Chroot + "/consumers"
 }
 
 func (k *KeyBuilder) consumer(name string) string {
-	if k.ClusterID == "" {
-		return fmt.Sprintf("/consumers/%s", name)
-	}
-	return fmt.Sprintf("/%s/consumers/%s", k.ClusterID, name)
+	return fmt.Sprintf("%s/consumers/%s", k.Chroot, name)
 }
 
 func (k *KeyBuilder) consumerOffsets(consumer string, topic string) string {
-	if k.ClusterID == "" {
-		return fmt.Sprintf("/consumers/%s/offsets/%s", consumer, topic)
-	}
-	return fmt.Sprintf("/%s/consumers/%s/offsets/%s", k.ClusterID, consumer, topic)
+	return fmt.Sprintf("%s/consumers/%s/offsets/%s", k.Chroot, consumer, topic)
+}
+
+func (k *KeyBuilder) consumerIDs(consumer string) string {
+	return fmt.Sprintf("%s/consumers/%s/ids", k.Chroot, consumer)
+}
+
+func (k *KeyBuilder) consumerTopics(consumer string) string {
+	return fmt.Sprintf("%s/consumers/%s/offsets", k.Chroot, consumer)
+}
+
+func (k *KeyBuilder) consumerOffset(consumer string, topic string, partitionID string) string {
+	return fmt.Sprintf("%s/consumers/%s/offsets/%s/%s", k.Chroot, consumer, topic, partitionID)
+}
+
+func (k *KeyBuilder) controller() string {
+	return k.Chroot + "/controller"
+}
+
+func (k *KeyBuilder) controllerEpoch() string {
+	return k.Chroot + "/controller_epoch"
+}
+
+func (k *KeyBuilder) reassignPartitions() string {
+	return k.Chroot + "/admin/reassign_partitions"
+}
+
+func (k *KeyBuilder) preferredReplicaElection() string {
+	return k.Chroot + "/admin/preferred_replica_election"
+}
+
+func (k *KeyBuilder) deleteTopics() string {
+	return k.Chroot + "/admin/delete_topics"
+}
+
+func (k *KeyBuilder) deleteTopic(name string) string {
+	return fmt.Sprintf("%s/admin/delete_topics/%s", k.Chroot, name)
+}
+
+func (k *KeyBuilder) topicConfig(topic string) string {
+	return fmt.Sprintf("%s/config/topics/%s", k.Chroot, topic)
+}
+
+func (k *KeyBuilder) configChanges() string {
+	return k.Chroot + "/config/changes"
+}
+
+func (k *KeyBuilder) configChange(name string) string {
+	return fmt.Sprintf("%s/config/changes/%s", k.Chroot, name)
 }
@@ -0,0 +1,43 @@
+package ktop
+
+import (
+	"testing"
+)
+
+func TestKeyBuilder(t *testing.T) {
+	tests := []struct {
+		chroot string
+		path   func(k *KeyBuilder) string
+		want   string
+	}{
+		{"", (*KeyBuilder).cluster, "/"},
+		{"/kafka/prod", (*KeyBuilder).cluster, "/kafka/prod"},
+		{"", (*KeyBuilder).brokers, "/brokers/ids"},
+		{"/kafka/prod", (*KeyBuilder).brokers, "/kafka/prod/brokers/ids"},
+		{"", func(k *KeyBuilder) string { return k.broker("1") }, "/brokers/ids/1"},
+		{"/kafka", func(k *KeyBuilder) string { return k.broker("1") }, "/kafka/brokers/ids/1"},
+		{"", func(k *KeyBuilder) string { return k.topic("orders") }, "/brokers/topics/orders"},
+		{"/kafka", func(k *KeyBuilder) string { return k.topic("orders") }, "/kafka/brokers/topics/orders"},
+		{"", func(k *KeyBuilder) string { return k.partitionState("orders", "3") }, "/brokers/topics/orders/partitions/3/state"},
+		{"/kafka/prod", func(k *KeyBuilder) string { return k.partitionState("orders", "3") }, "/kafka/prod/brokers/topics/orders/partitions/3/state"},
+		{"", func(k *KeyBuilder) string { return k.consumerOffset("group", "orders", "3") }, "/consumers/group/offsets/orders/3"},
+		{"/kafka", func(k *KeyBuilder) string { return k.consumerOffset("group", "orders", "3") }, "/kafka/consumers/group/offsets/orders/3"},
+		{"", (*KeyBuilder).controller, "/controller"},
+		{"/kafka", (*KeyBuilder).controller, "/kafka/controller"},
+		{"", (*KeyBuilder).reassignPartitions, "/admin/reassign_partitions"},
+		{"/kafka", (*KeyBuilder).reassignPartitions, "/kafka/admin/reassign_partitions"},
+		{"", func(k *KeyBuilder) string { return k.deleteTopic("orders") }, "/admin/delete_topics/orders"},
+		{"/kafka", func(k *KeyBuilder) string { return k.deleteTopic("orders") }, "/kafka/admin/delete_topics/orders"},
+		{"", func(k *KeyBuilder) string { return k.topicConfig("orders") }, "/config/topics/orders"},
+		{"/kafka", func(k *KeyBuilder) string { return k.topicConfig("orders") }, "/kafka/config/topics/orders"},
+		{"", (*KeyBuilder).configChanges, "/config/changes"},
+		{"/kafka", (*KeyBuilder).configChanges, "/kafka/config/changes"},
+	}
+
+	for _, test := range tests {
+		k := &KeyBuilder{Chroot: test.chroot}
+		if got := test.path(k); got != test.want {
+			t.Errorf("chroot %q: %s, expected %s", test.chroot, got, test.want)
+		}
+	}
+}
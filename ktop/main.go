@@ -1,23 +1,258 @@
 package main
 
 import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
 	"os"
+	"strings"
 
 	"bitbucket.org/yichen/ktop"
 )
 
+// clusterFlags are the flags selecting the cluster, shared by the commands
+type clusterFlags struct {
+	configFile *string
+	brokers    *string
+	defaults   *string
+}
+
+func addClusterFlags(fs *flag.FlagSet) *clusterFlags {
+	return &clusterFlags{
+		configFile: fs.String("config", ktop.DefaultConfigFile(), "config file of the named clusters"),
+		brokers:    fs.String("brokers", "", "comma separated bootstrap brokers, to run without ZooKeeper"),
+		defaults:   fs.String("defaults", "", "broker server.properties, to compare the topic config overrides with"),
+	}
+}
+
+// selectCluster loads the config, and returns the cluster to start with: a
+// cluster given on the command line, a cluster of the config by name, or the
+// default cluster of the config. It returns an empty name if there is none.
+// configured is false when the cluster is not in the config file, and was
+// added from the -brokers flag or the ZooKeeper URL on the command line
+func (f *clusterFlags) selectCluster(args []string) (config *ktop.Config, name string, configured bool) {
+	config, err := ktop.LoadConfig(*f.configFile)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(1)
+	}
+
+	switch {
+	case *f.brokers != "":
+		config.Clusters = append(config.Clusters, ktop.ClusterConfig{
+			Name:           *f.brokers,
+			Brokers:        strings.Split(*f.brokers, ","),
+			BrokerDefaults: *f.defaults,
+		})
+		return config, *f.brokers, false
+
+	case len(args) > 0:
+		if _, ok := config.Cluster(args[0]); ok {
+			return config, args[0], true
+		}
+		config.Clusters = append(config.Clusters, ktop.ClusterConfig{
+			Name:           args[0],
+			Zookeeper:      args[0],
+			BrokerDefaults: *f.defaults,
+		})
+		return config, args[0], false
+
+	case config.Default != "":
+		return config, config.Default, true
+
+	case len(config.Clusters) > 0:
+		return config, config.Clusters[0].Name, true
+	}
+
+	return config, "", false
+}
+
 func main() {
-	// zk: eat1-app397.stg.linkedin.com:12913/kafka-cluster
-	var zkstr string
-	args := os.Args
-	if len(args) == 1 {
-		// fmt.Println("Wrong argument. A seed broker URL is required.")
-		//  "eat1-app1252.corp.linkedin.com:10251"
-		// zkstr = "eat1-app397.stg.linkedin.com:12913/kafka-cluster"
-		zkstr = "zk-ei1-kafka.stg.linkedin.com:12913/kafka-espresso-testing"
+	if len(os.Args) > 1 && os.Args[1] == "snapshot" {
+		snapshot(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "diff" {
+		diff(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "plan" {
+		plan(os.Args[2:])
+		return
+	}
+
+	cf := addClusterFlags(flag.CommandLine)
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: ktop [options] {zookeeperserver:port}[,{zookeeperserver:port}...][/{chroot}]")
+		fmt.Fprintln(os.Stderr, "       ktop [options] -brokers {broker:port}[,{broker:port}...]")
+		fmt.Fprintln(os.Stderr, "       ktop [options] [{cluster name}]")
+		fmt.Fprintln(os.Stderr, "       ktop snapshot [options] [{cluster}]")
+		fmt.Fprintln(os.Stderr, "       ktop diff [options] {snapshot} [{snapshot}]")
+		fmt.Fprintln(os.Stderr, "       ktop plan [options] -topics {topic}[,{topic}...] -to {broker id}[,{broker id}...] [{cluster}]")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	config, name, _ := cf.selectCluster(flag.Args())
+	if name == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	ktop.Start(config, name)
+}
+
+// snapshot writes the metadata snapshot of a cluster to a JSON file
+func snapshot(args []string) {
+	fs := flag.NewFlagSet("snapshot", flag.ExitOnError)
+	cf := addClusterFlags(fs)
+	output := fs.String("o", "", "file to write, ktop-snapshot-{cluster}-{time}.json by default")
+	offsets := fs.Bool("offsets", false, "include the log start and end offset of every partition")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: ktop snapshot [options] [{cluster}]")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	config, name, _ := cf.selectCluster(fs.Args())
+	cc, ok := config.Cluster(name)
+	if !ok {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	path, err := ktop.SaveSnapshot(cc, *output, *offsets)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(1)
+	}
+	fmt.Println("snapshot written to " + path)
+}
+
+// diff prints what changed between two snapshots, or between a snapshot and
+// the live cluster
+func diff(args []string) {
+	fs := flag.NewFlagSet("diff", flag.ExitOnError)
+	cf := addClusterFlags(fs)
+	clusterName := fs.String("cluster", "", "live cluster to compare with, the cluster of the snapshot by default")
+	asJSON := fs.Bool("json", false, "print the diff as JSON")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: ktop diff [options] {snapshot} [{snapshot}]")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() < 1 || fs.NArg() > 2 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	from, err := ktop.ReadSnapshot(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(1)
+	}
+
+	var to *ktop.Snapshot
+	if fs.NArg() == 2 {
+		to, err = ktop.ReadSnapshot(fs.Arg(1))
 	} else {
-		zkstr = args[1]
+		name := *clusterName
+		if name == "" {
+			name = from.Cluster
+		}
+		config, name, configured := cf.selectCluster([]string{name})
+		cc, _ := config.Cluster(name)
+		if *clusterName == "" && *cf.brokers == "" && len(from.Bootstrap) > 0 && !configured {
+			// not a cluster of the config, and taken without ZooKeeper:
+			// the name is the bootstrap brokers
+			cc = ktop.ClusterConfig{
+				Name:           from.Cluster,
+				Brokers:        from.Bootstrap,
+				BrokerDefaults: *cf.defaults,
+			}
+		}
+		to, err = ktop.LiveSnapshot(cc, false)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(1)
+	}
+
+	d := ktop.DiffSnapshots(from, to)
+	if *asJSON {
+		data, err := json.MarshalIndent(d, "", "  ")
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			os.Exit(1)
+		}
+		fmt.Println(string(data))
+		return
+	}
+
+	for _, line := range d.Lines() {
+		fmt.Println(line)
+	}
+}
+
+// plan writes a kafka-reassign-partitions plan moving topics to a set of
+// brokers, and prints the current and proposed replicas side by side
+func plan(args []string) {
+	fs := flag.NewFlagSet("plan", flag.ExitOnError)
+	cf := addClusterFlags(fs)
+	topics := fs.String("topics", "", "comma separated topics to reassign")
+	to := fs.String("to", "", "comma separated IDs of the brokers to spread the replicas on")
+	output := fs.String("o", "reassignment.json", "file to write the plan to")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: ktop plan [options] -topics {topic}[,{topic}...] -to {broker id}[,{broker id}...] [{cluster}]")
+		fs.PrintDefaults()
 	}
+	fs.Parse(args)
 
-	ktop.Start(zkstr)
+	brokers, err := ktop.ParseBrokerIDs(*to)
+	if err != nil || *topics == "" || len(brokers) == 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	config, name, _ := cf.selectCluster(fs.Args())
+	cc, ok := config.Cluster(name)
+	if !ok {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	cluster, err := cc.Connect()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(1)
+	}
+	defer cluster.Close()
+
+	current, err := ktop.CurrentAssignments(cluster, strings.Split(*topics, ","))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(1)
+	}
+
+	p, err := ktop.PlanReassignment(current, brokers)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(1)
+	}
+
+	for _, line := range p.Lines() {
+		fmt.Println(line)
+	}
+
+	data, err := p.JSON()
+	if err == nil {
+		err = ioutil.WriteFile(*output, append(data, '\n'), 0644)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(1)
+	}
+	fmt.Println("plan written to " + *output)
 }
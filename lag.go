@@ -0,0 +1,246 @@
+package ktop
+
+import (
+	"log"
+	"sort"
+
+	"github.com/Shopify/sarama"
+)
+
+// storage of the committed offsets
+const (
+	StorageZookeeper = "zookeeper"
+	StorageKafka     = "kafka"
+)
+
+// PartitionLag compares the offset committed by a consumer group for a
+// partition with the log end offset of the partition
+type PartitionLag struct {
+	Topic     string
+	Partition int32
+
+	// where the offset is committed, StorageZookeeper or StorageKafka
+	Storage string
+
+	// offset committed by the consumer group, -1 if unknown
+	Committed int64
+
+	// offset of the next message produced to the partition, -1 if unknown
+	LogEnd int64
+}
+
+// Lag returns the number of messages the group is behind, or -1 if either
+// offset is unknown
+func (p PartitionLag) Lag() int64 {
+	if p.Committed < 0 || p.LogEnd < 0 {
+		return -1
+	}
+	return p.LogEnd - p.Committed
+}
+
+type PartitionLags []PartitionLag
+
+func (pl PartitionLags) Len() int {
+	return len(pl)
+}
+
+func (pl PartitionLags) Swap(i, j int) {
+	pl[i], pl[j] = pl[j], pl[i]
+}
+
+// Less sorts by topic and partition, with the offset committed to ZooKeeper
+// next to the one committed to Kafka for the same partition
+func (pl PartitionLags) Less(i, j int) bool {
+	if pl[i].Topic != pl[j].Topic {
+		return pl[i].Topic < pl[j].Topic
+	}
+	if pl[i].Partition != pl[j].Partition {
+		return pl[i].Partition < pl[j].Partition
+	}
+	return pl[i].Storage > pl[j].Storage
+}
+
+// TotalLag sums the known lag of the partitions whose offsets are committed
+// to the storage
+func (pl PartitionLags) TotalLag(storage string) int64 {
+	var total int64
+	for _, p := range pl {
+		if lag := p.Lag(); lag > 0 && p.Storage == storage {
+			total += lag
+		}
+	}
+	return total
+}
+
+// ConsumerLag computes the lag of a consumer group for every partition it
+// committed offsets for, both in ZooKeeper and in Kafka. The Kafka-committed
+// offsets are fetched for the topics the group has in ZooKeeper, or for all
+// topics if the group is not registered in ZooKeeper
+func ConsumerLag(cluster *Cluster, client sarama.Client, group string) (PartitionLags, error) {
+	zkOffsets, err := cluster.ConsumerOffsets(group)
+	if err == ErrNoZookeeper {
+		zkOffsets = map[string]map[int32]int64{}
+	} else if err != nil {
+		return nil, err
+	}
+
+	topics := make([]string, 0, len(zkOffsets))
+	for topic := range zkOffsets {
+		topics = append(topics, topic)
+	}
+	if len(topics) == 0 {
+		topics, err = client.Topics()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	kafkaOffsets, err := kafkaCommittedOffsets(client, group, topics)
+	if err != nil {
+		// the group may not commit to Kafka at all, or the cluster is older
+		// than 0.8.2. The ZooKeeper offsets are still worth showing
+		log.Println("failed to fetch the Kafka offsets of consumer group " + group + ": " + err.Error())
+	}
+
+	partitions := make(map[string][]int32)
+	for _, committed := range []map[string]map[int32]int64{zkOffsets, kafkaOffsets} {
+		for topic, offsets := range committed {
+			for p := range offsets {
+				partitions[topic] = append(partitions[topic], p)
+			}
+		}
+	}
+
+	logEnd := logEndOffsets(client, partitions)
+
+	lags := PartitionLags{}
+	lags = appendLags(lags, StorageZookeeper, zkOffsets, logEnd)
+	lags = appendLags(lags, StorageKafka, kafkaOffsets, logEnd)
+	sort.Sort(lags)
+
+	return lags, nil
+}
+
+func appendLags(lags PartitionLags, storage string, committed map[string]map[int32]int64, logEnd map[string]map[int32]int64) PartitionLags {
+	for topic, offsets := range committed {
+		for p, offset := range offsets {
+			end, ok := logEnd[topic][p]
+			if !ok {
+				end = -1
+			}
+			lags = append(lags, PartitionLag{
+				Topic:     topic,
+				Partition: p,
+				Storage:   storage,
+				Committed: offset,
+				LogEnd:    end,
+			})
+		}
+	}
+	return lags
+}
+
+// kafkaCommittedOffsets fetches the offsets a consumer group committed to
+// Kafka from the offset coordinator of the group. Partitions without a
+// committed offset are left out of the result
+func kafkaCommittedOffsets(client sarama.Client, group string, topics []string) (map[string]map[int32]int64, error) {
+	// the coordinator is found with a ConsumerMetadataRequest
+	coordinator, err := client.Coordinator(group)
+	if err != nil {
+		return nil, err
+	}
+
+	// version 1 reads the offsets stored in Kafka, version 0 the ones
+	// stored in ZooKeeper
+	req := &sarama.OffsetFetchRequest{
+		ConsumerGroup: group,
+		Version:       1,
+	}
+
+	for _, topic := range topics {
+		partitions, err := client.Partitions(topic)
+		if err != nil {
+			log.Println("failed to find the partitions of topic " + topic + ": " + err.Error())
+			continue
+		}
+		for _, p := range partitions {
+			req.AddPartition(topic, p)
+		}
+	}
+
+	resp, err := coordinator.FetchOffset(req)
+	if err != nil {
+		return nil, err
+	}
+
+	offsets := make(map[string]map[int32]int64)
+	for topic, blocks := range resp.Blocks {
+		for p, block := range blocks {
+			if block.Err != sarama.ErrNoError || block.Offset < 0 {
+				continue
+			}
+			if offsets[topic] == nil {
+				offsets[topic] = make(map[int32]int64)
+			}
+			offsets[topic][p] = block.Offset
+		}
+	}
+
+	return offsets, nil
+}
+
+// logEndOffsets asks the leader of each partition for its log end offset
+func logEndOffsets(client sarama.Client, partitions map[string][]int32) map[string]map[int32]int64 {
+	return partitionOffsets(client, partitions, sarama.OffsetNewest)
+}
+
+// partitionOffsets asks the leader of each partition for its offset at the
+// given time, sarama.OffsetNewest or sarama.OffsetOldest, sending one
+// OffsetRequest per leader. Partitions whose leader cannot be reached are left
+// out of the result
+func partitionOffsets(client sarama.Client, partitions map[string][]int32, time int64) map[string]map[int32]int64 {
+	requests := make(map[int32]*sarama.OffsetRequest)
+	leaders := make(map[int32]*sarama.Broker)
+
+	for topic, ids := range partitions {
+		for _, p := range ids {
+			leader, err := client.Leader(topic, p)
+			if err != nil {
+				log.Printf("no leader for %s:%d: %v", topic, p, err)
+				continue
+			}
+
+			req, ok := requests[leader.ID()]
+			if !ok {
+				req = &sarama.OffsetRequest{}
+				requests[leader.ID()] = req
+				leaders[leader.ID()] = leader
+			}
+			req.AddBlock(topic, p, time, 1)
+		}
+	}
+
+	offsets := make(map[string]map[int32]int64)
+	for id, req := range requests {
+		resp, err := leaders[id].GetAvailableOffsets(req)
+		if err != nil {
+			log.Printf("failed to get offsets from broker %d: %v", id, err)
+			continue
+		}
+
+		for topic, blocks := range resp.Blocks {
+			for p, block := range blocks {
+				if block.Err != sarama.ErrNoError || len(block.Offsets) == 0 {
+					log.Printf("no offset for %s:%d: %v", topic, p, block.Err)
+					continue
+				}
+				if offsets[topic] == nil {
+					offsets[topic] = make(map[int32]int64)
+				}
+				offsets[topic][p] = block.Offsets[0]
+			}
+		}
+	}
+
+	return offsets
+}
@@ -0,0 +1,128 @@
+package ktop
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/Shopify/sarama"
+	"github.com/nsf/termbox-go"
+)
+
+// LagScreen shows the committed offset, log end offset and lag of a consumer
+// group for each partition it reads, with totals per topic and per group
+type LagScreen struct {
+	listCursor
+
+	group string
+	lags  PartitionLags
+
+	// the lines to print, partitions followed by the total of their topic
+	// for each storage
+	lines []string
+
+	client  sarama.Client
+	cluster *Cluster
+}
+
+func NewLagScreen(cluster *Cluster, client sarama.Client, group string) *LagScreen {
+	return &LagScreen{
+		cluster: cluster,
+		client:  client,
+		group:   group,
+	}
+}
+
+func (s *LagScreen) WillShow(screen Screen) error {
+	lags, err := ConsumerLag(s.cluster, s.client, s.group)
+	if err != nil {
+		return fmt.Errorf("failed to compute the lag of consumer group %s: %v", s.group, err)
+	}
+	s.lags = lags
+
+	s.lines = s.lines[:0]
+	topicLag := make(map[string]int64)
+	for i, p := range s.lags {
+		s.lines = append(s.lines, fmt.Sprintf("%-40s %10d %-10s %15s %15s %12s",
+			p.Topic, p.Partition, p.Storage, formatOffset(p.Committed), formatOffset(p.LogEnd), formatOffset(p.Lag())))
+
+		if _, ok := topicLag[p.Storage]; !ok {
+			topicLag[p.Storage] = 0
+		}
+		if lag := p.Lag(); lag > 0 {
+			topicLag[p.Storage] += lag
+		}
+
+		// close the topic with its total for each storage
+		if i == len(s.lags)-1 || s.lags[i+1].Topic != p.Topic {
+			for _, storage := range []string{StorageZookeeper, StorageKafka} {
+				if lag, ok := topicLag[storage]; ok {
+					s.lines = append(s.lines, fmt.Sprintf("%-40s %10s %-10s %15s %15s %12d", "", "total", storage, "", "", lag))
+				}
+			}
+			topicLag = make(map[string]int64)
+		}
+	}
+	s.clamp(len(s.lines))
+	return nil
+}
+
+func (s *LagScreen) Refresh(screen Screen) {
+	termbox.Clear(coldef, coldef)
+	w, h = termbox.Size()
+
+	summary := "Consumer Group: " + s.group +
+		", Total Lag: " + strconv.FormatInt(s.lags.TotalLag(StorageZookeeper), 10) + " (zookeeper), " +
+		strconv.FormatInt(s.lags.TotalLag(StorageKafka), 10) + " (kafka)"
+	screen.Print(summary, 0, 0, coldef, coldef)
+
+	titles := fmt.Sprintf("     %-40s %10s %-10s %15s %15s %12s", "TOPIC", "PARTITION", "STORAGE", "COMMITTED", "LOG END", "LAG")
+	screen.Print(titles, 0, 2, coldef, coldef)
+
+	first, last := s.visible(len(s.lines), h-3)
+	for i := first; i < last; i++ {
+		screen.Print(s.lines[i], 5, i-s.Position+3, coldef, coldef)
+	}
+
+	if len(s.lines) > 0 {
+		screen.Print(" -> ", 0, s.Cursor-s.Position+3, coldef, coldef)
+	}
+
+	termbox.HideCursor()
+	screen.Flush()
+}
+
+func (s *LagScreen) OnKeyInput(screen Screen, keyEvent termbox.Event) {
+	_, h = termbox.Size()
+
+	switch keyEvent.Type {
+	case termbox.EventKey:
+		switch keyEvent.Key {
+		case termbox.KeyArrowLeft, termbox.KeyCtrlQ:
+			screen.Pop()
+
+		case termbox.KeyCtrlR:
+			// reload the offsets
+			if err := s.WillShow(screen); err != nil {
+				screen.SetError(err)
+			} else {
+				screen.SetStatus("")
+			}
+			s.Refresh(screen)
+
+		default:
+			if s.onKey(keyEvent.Key, len(s.lines), h-3) {
+				s.Refresh(screen)
+			}
+		}
+	case termbox.EventError:
+		screen.SetError(keyEvent.Err)
+		screen.Flush()
+	}
+}
+
+func formatOffset(offset int64) string {
+	if offset < 0 {
+		return "-"
+	}
+	return strconv.FormatInt(offset, 10)
+}
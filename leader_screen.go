@@ -0,0 +1,108 @@
+package ktop
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/Shopify/sarama"
+	"github.com/nsf/termbox-go"
+)
+
+// LeaderScreen reports the leader skew of the cluster: the partitions each
+// broker leads compared with the partitions it is the preferred leader of,
+// and the partitions not led by their preferred replica
+type LeaderScreen struct {
+	listCursor
+
+	report LeaderReport
+	lines  []string
+
+	client  sarama.Client
+	cluster *Cluster
+	broker  string
+}
+
+func NewLeaderScreen(cluster *Cluster, client sarama.Client, broker string) *LeaderScreen {
+	return &LeaderScreen{
+		cluster: cluster,
+		client:  client,
+		broker:  broker,
+	}
+}
+
+func (s *LeaderScreen) WillShow(screen Screen) error {
+	metadata, err := fetchMetadata(s.cluster.SeedBrokers())
+	if err != nil {
+		return fmt.Errorf("failed to get the metadata of the cluster: %v", err)
+	}
+
+	s.report = leaderBalance(metadata)
+
+	s.lines = []string{fmt.Sprintf("%8s %-30s %8s %10s %8s", "BROKER", "HOST", "LEADS", "PREFERRED", "SKEW")}
+	for _, b := range s.report.Brokers {
+		s.lines = append(s.lines, fmt.Sprintf("%8d %-30s %8d %10d %+8d",
+			b.BrokerID, s.cluster.Broker(strconv.Itoa(int(b.BrokerID))), b.Leads, b.Preferred, b.Leads-b.Preferred))
+	}
+
+	s.lines = append(s.lines, "", fmt.Sprintf("%-40s %8s %10s  %s", "PARTITION", "LEADER", "PREFERRED", "REPLICAS"))
+	for _, p := range s.report.Misled {
+		s.lines = append(s.lines, fmt.Sprintf("%-40s %8d %10d  %s",
+			fmt.Sprintf("%s:%d", p.Topic, p.Partition), p.Leader, p.Preferred, formatBrokerIDs(p.Replicas)))
+	}
+
+	s.clamp(len(s.lines))
+	return nil
+}
+
+func (s *LeaderScreen) Refresh(screen Screen) {
+	termbox.Clear(coldef, coldef)
+	w, h = termbox.Size()
+
+	summary := fmt.Sprintf("Partitions not led by their preferred replica: %d", len(s.report.Misled))
+	fg := coldef
+	if len(s.report.Misled) > 0 {
+		summary += ", a preferred replica election is needed"
+		fg = termbox.ColorYellow
+	}
+	screen.Print(summary, 0, 0, fg, coldef)
+
+	first, last := s.visible(len(s.lines), h-3)
+	for i := first; i < last; i++ {
+		screen.Print(s.lines[i], 5, i-s.Position+2, coldef, coldef)
+	}
+
+	if len(s.lines) > 0 {
+		screen.Print(" -> ", 0, s.Cursor-s.Position+2, coldef, coldef)
+	}
+
+	termbox.HideCursor()
+	screen.Flush()
+}
+
+func (s *LeaderScreen) OnKeyInput(screen Screen, keyEvent termbox.Event) {
+	_, h = termbox.Size()
+
+	switch keyEvent.Type {
+	case termbox.EventKey:
+		switch keyEvent.Key {
+		case termbox.KeyArrowLeft, termbox.KeyCtrlQ:
+			screen.Pop()
+
+		case termbox.KeyCtrlR:
+			if err := s.WillShow(screen); err != nil {
+				screen.SetError(err)
+			} else {
+				screen.SetStatus("")
+			}
+			s.Refresh(screen)
+
+		default:
+			if s.onKey(keyEvent.Key, len(s.lines), h-3) {
+				s.Refresh(screen)
+			}
+		}
+	case termbox.EventError:
+		screen.SetError(keyEvent.Err)
+		screen.Flush()
+	}
+}
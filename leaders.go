@@ -0,0 +1,117 @@
+package ktop
+
+import (
+	"sort"
+
+	"github.com/Shopify/sarama"
+)
+
+// BrokerLeadership counts the partitions a broker leads, and the partitions
+// it is the preferred leader of
+type BrokerLeadership struct {
+	BrokerID  int32
+	Leads     int
+	Preferred int
+}
+
+type BrokerLeaderships []BrokerLeadership
+
+func (bl BrokerLeaderships) Len() int {
+	return len(bl)
+}
+
+func (bl BrokerLeaderships) Swap(i, j int) {
+	bl[i], bl[j] = bl[j], bl[i]
+}
+
+func (bl BrokerLeaderships) Less(i, j int) bool {
+	return bl[i].BrokerID < bl[j].BrokerID
+}
+
+// MisledPartition is a partition whose current leader is not its preferred
+// replica, the first replica of its replica list
+type MisledPartition struct {
+	Topic     string
+	Partition int32
+	Leader    int32
+	Preferred int32
+	Replicas  []int32
+}
+
+type MisledPartitions []MisledPartition
+
+func (mp MisledPartitions) Len() int {
+	return len(mp)
+}
+
+func (mp MisledPartitions) Swap(i, j int) {
+	mp[i], mp[j] = mp[j], mp[i]
+}
+
+func (mp MisledPartitions) Less(i, j int) bool {
+	if mp[i].Topic != mp[j].Topic {
+		return mp[i].Topic < mp[j].Topic
+	}
+	return mp[i].Partition < mp[j].Partition
+}
+
+// LeaderReport compares the partitions each broker leads with the partitions
+// it should lead, and lists the partitions not led by their preferred replica
+type LeaderReport struct {
+	Brokers BrokerLeaderships
+	Misled  MisledPartitions
+}
+
+// leaderBalance builds the LeaderReport of the cluster from its metadata.
+// Offline partitions are left out, they have no leader to move
+func leaderBalance(metadata *sarama.MetadataResponse) LeaderReport {
+	brokers := make(map[int32]*BrokerLeadership)
+	for _, b := range metadata.Brokers {
+		brokers[b.ID()] = &BrokerLeadership{BrokerID: b.ID()}
+	}
+	broker := func(id int32) *BrokerLeadership {
+		if _, ok := brokers[id]; !ok {
+			brokers[id] = &BrokerLeadership{BrokerID: id}
+		}
+		return brokers[id]
+	}
+
+	report := LeaderReport{
+		Brokers: BrokerLeaderships{},
+		Misled:  MisledPartitions{},
+	}
+
+	for _, t := range metadata.Topics {
+		for _, p := range t.Partitions {
+			if len(p.Replicas) == 0 {
+				continue
+			}
+
+			preferred := p.Replicas[0]
+			broker(preferred).Preferred++
+
+			if p.Leader < 0 {
+				continue
+			}
+			broker(p.Leader).Leads++
+
+			if p.Leader != preferred {
+				report.Misled = append(report.Misled, MisledPartition{
+					Topic:     t.Name,
+					Partition: p.ID,
+					Leader:    p.Leader,
+					Preferred: preferred,
+					Replicas:  p.Replicas,
+				})
+			}
+		}
+	}
+
+	for _, b := range brokers {
+		report.Brokers = append(report.Brokers, *b)
+	}
+	sort.Sort(report.Brokers)
+	sort.Sort(report.Misled)
+
+	return report
+}
@@ -0,0 +1,101 @@
+package ktop
+
+import "github.com/nsf/termbox-go"
+
+// listCursor keeps track of the navigation in a scrollable list, the same way
+// TopicScreen does for the topic list
+type listCursor struct {
+	// position in the list that is currently the begining of the screen
+	Position int
+
+	// position in the list that is currently pointed by the cursor
+	Cursor int
+}
+
+// onKey moves the cursor for the navigation keys and returns true if the key
+// was handled. size is the number of items in the list, and page is the number
+// of rows available to show the list
+func (l *listCursor) onKey(key termbox.Key, size int, page int) bool {
+	if page < 1 {
+		page = 1
+	}
+
+	switch key {
+	case termbox.KeyArrowDown:
+		if l.Cursor >= size-1 {
+			return true
+		}
+		l.Cursor++
+		if l.Cursor >= l.Position+page {
+			l.Position += page / 2
+			if l.Position > l.Cursor {
+				l.Position = l.Cursor
+			}
+		}
+
+	case termbox.KeyArrowUp:
+		if l.Cursor == 0 {
+			return true
+		}
+		l.Cursor--
+		if l.Cursor < l.Position {
+			l.Position -= page / 2
+			if l.Position > l.Cursor || l.Position < 0 {
+				l.Position = l.Cursor
+			}
+		}
+
+	case termbox.KeyCtrlF, termbox.KeyPgdn:
+		if size <= page {
+			return true
+		}
+		l.Position += page
+		l.Cursor += page
+		if l.Position >= size {
+			l.Position = size - page/2
+		}
+		if l.Cursor >= size {
+			l.Cursor = size - 1
+		}
+
+	case termbox.KeyCtrlB, termbox.KeyPgup:
+		if size <= page {
+			return true
+		}
+		l.Position -= page
+		l.Cursor -= page
+		if l.Position < 0 {
+			l.Position = 0
+		}
+		if l.Cursor < 0 {
+			l.Cursor = 0
+		}
+
+	default:
+		return false
+	}
+
+	return true
+}
+
+// clamp keeps the cursor inside a list whose size may have changed
+func (l *listCursor) clamp(size int) {
+	if l.Cursor >= size {
+		l.Cursor = size - 1
+	}
+	if l.Cursor < 0 {
+		l.Cursor = 0
+	}
+	if l.Position > l.Cursor {
+		l.Position = l.Cursor
+	}
+}
+
+// visible returns the range of list items shown on a page
+func (l *listCursor) visible(size int, page int) (int, int) {
+	last := l.Position + page
+	if last > size {
+		last = size
+	}
+	return l.Position, last
+}
@@ -0,0 +1,442 @@
+package ktop
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Shopify/sarama"
+)
+
+// ReassignmentPlan moves the replicas of a set of partitions to a set of
+// brokers. Current and Proposed have the same partitions, in the same order
+type ReassignmentPlan struct {
+	Brokers  []int32
+	Current  PartitionReplicasList
+	Proposed PartitionReplicasList
+}
+
+// ParseBrokerIDs parses a comma separated list of broker IDs
+func ParseBrokerIDs(text string) ([]int32, error) {
+	ids := []int32{}
+	for _, field := range strings.Split(text, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		id, err := strconv.ParseInt(field, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid broker ID %q", field)
+		}
+		ids = append(ids, int32(id))
+	}
+	return ids, nil
+}
+
+// CurrentAssignments returns the replicas of every partition of the topics,
+// from ZooKeeper when available, or from the metadata of the brokers. A topic
+// given more than once is only listed once
+func CurrentAssignments(cluster *Cluster, topics []string) (PartitionReplicasList, error) {
+	assignments := PartitionReplicasList{}
+
+	seen := make(map[string]bool)
+	unique := []string{}
+	for _, topic := range topics {
+		topic = strings.TrimSpace(topic)
+		if topic != "" && !seen[topic] {
+			seen[topic] = true
+			unique = append(unique, topic)
+		}
+	}
+	topics = unique
+
+	if cluster.HasZookeeper() {
+		for _, topic := range topics {
+			assignment, err := cluster.Topic(topic)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read the assignment of topic %s: %v", topic, err)
+			}
+			for _, id := range assignment.PartitionIDs() {
+				assignments = append(assignments, PartitionReplicas{topic, id, assignment.Replicas[id]})
+			}
+		}
+		sort.Sort(assignments)
+		return assignments, nil
+	}
+
+	metadata, err := fetchMetadata(cluster.SeedBrokers(), topics...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the metadata of the topics: %v", err)
+	}
+	for _, t := range metadata.Topics {
+		if t.Err != sarama.ErrNoError {
+			return nil, fmt.Errorf("failed to get the metadata of topic %s: %v", t.Name, t.Err)
+		}
+		for _, p := range t.Partitions {
+			assignments = append(assignments, PartitionReplicas{t.Name, p.ID, p.Replicas})
+		}
+	}
+	sort.Sort(assignments)
+	return assignments, nil
+}
+
+// PlanReassignment spreads the replicas of the partitions evenly on the
+// brokers, and makes each broker the preferred leader of as many partitions
+// as the others, give or take one. Replicas already on a broker that is not
+// over its share stay where they are when the balance allows it, to move as
+// little data as possible. The replication factor of each partition is kept
+func PlanReassignment(current PartitionReplicasList, brokers []int32) (*ReassignmentPlan, error) {
+	if len(brokers) == 0 {
+		return nil, errors.New("no target broker")
+	}
+
+	targets := make(PartitionIDs, 0, len(brokers))
+	for _, id := range brokers {
+		if !containsBrokerID(targets, id) {
+			targets = append(targets, id)
+		}
+	}
+	sort.Sort(targets)
+
+	replicaCount := 0
+	for _, p := range current {
+		if len(p.Replicas) > len(targets) {
+			return nil, fmt.Errorf("partition %s:%d has %d replicas, more than the %d target brokers",
+				p.Topic, p.Partition, len(p.Replicas), len(targets))
+		}
+		for j, id := range p.Replicas {
+			if containsBrokerID(p.Replicas[:j], id) {
+				return nil, fmt.Errorf("partition %s:%d has broker %d twice in its replicas %v",
+					p.Topic, p.Partition, id, p.Replicas)
+			}
+		}
+		replicaCount += len(p.Replicas)
+	}
+
+	proposed := make(PartitionReplicasList, len(current))
+
+	// keep the replicas on the target brokers within their share, then place
+	// the missing replicas on the least loaded brokers
+	replicas := newBrokerShares(targets, replicaCount)
+	for i, p := range current {
+		proposed[i] = PartitionReplicas{Topic: p.Topic, Partition: p.Partition, Replicas: []int32{}}
+		for _, id := range p.Replicas {
+			if replicas.take(id) {
+				proposed[i].Replicas = append(proposed[i].Replicas, id)
+			}
+		}
+	}
+	for i, p := range current {
+		for len(proposed[i].Replicas) < len(p.Replicas) {
+			id := replicas.leastLoaded(proposed[i].Replicas)
+			replicas.count[id]++
+			proposed[i].Replicas = append(proposed[i].Replicas, id)
+		}
+	}
+	if err := balanceReplicas(current, proposed, replicas); err != nil {
+		return nil, err
+	}
+
+	// keep the preferred leaders within their share, then pick the replica
+	// leading the fewest partitions
+	leaders := newBrokerShares(targets, len(current))
+	leader := make([]int32, len(current))
+	for i, p := range current {
+		leader[i] = -1
+		if len(p.Replicas) > 0 && containsBrokerID(proposed[i].Replicas, p.Replicas[0]) && leaders.take(p.Replicas[0]) {
+			leader[i] = p.Replicas[0]
+		}
+	}
+	for i := range proposed {
+		if leader[i] == -1 && len(proposed[i].Replicas) > 0 {
+			leader[i] = leaders.leastLoadedOf(proposed[i].Replicas)
+			leaders.count[leader[i]]++
+		}
+	}
+	balanceLeaders(proposed, leader, leaders)
+	for i := range proposed {
+		if leader[i] != -1 {
+			proposed[i].Replicas = withLeaderFirst(proposed[i].Replicas, leader[i])
+		}
+	}
+
+	return &ReassignmentPlan{
+		Brokers:  targets,
+		Current:  current,
+		Proposed: proposed,
+	}, nil
+}
+
+// brokerShares counts what each broker holds, against its even share of a
+// total. When the total does not divide evenly, the first brokers to reach
+// their share may go one over it
+type brokerShares struct {
+	brokers []int32
+	count   map[int32]int
+	share   int
+	extra   int
+}
+
+func newBrokerShares(brokers []int32, total int) *brokerShares {
+	return &brokerShares{
+		brokers: brokers,
+		count:   make(map[int32]int),
+		share:   total / len(brokers),
+		extra:   total % len(brokers),
+	}
+}
+
+// take counts one more for the broker if it is a target and within its share
+func (s *brokerShares) take(id int32) bool {
+	if !containsBrokerID(s.brokers, id) {
+		return false
+	}
+
+	switch {
+	case s.count[id] < s.share:
+	case s.count[id] == s.share && s.extra > 0:
+		s.extra--
+	default:
+		return false
+	}
+
+	s.count[id]++
+	return true
+}
+
+// leastLoaded returns the target broker with the lowest count, not in exclude
+func (s *brokerShares) leastLoaded(exclude []int32) int32 {
+	best := int32(-1)
+	for _, id := range s.brokers {
+		if containsBrokerID(exclude, id) {
+			continue
+		}
+		if best == -1 || s.count[id] < s.count[best] {
+			best = id
+		}
+	}
+	return best
+}
+
+// leastLoadedOf returns the broker with the lowest count among the given ones
+func (s *brokerShares) leastLoadedOf(ids []int32) int32 {
+	best := ids[0]
+	for _, id := range ids[1:] {
+		if s.count[id] < s.count[best] {
+			best = id
+		}
+	}
+	return best
+}
+
+// extremes returns the target brokers with the highest and the lowest count
+func (s *brokerShares) extremes() (int32, int32) {
+	most, fewest := s.brokers[0], s.brokers[0]
+	for _, id := range s.brokers[1:] {
+		if s.count[id] > s.count[most] {
+			most = id
+		}
+		if s.count[id] < s.count[fewest] {
+			fewest = id
+		}
+	}
+	return most, fewest
+}
+
+// balanceReplicas moves replicas from the most to the least loaded broker
+// until they are within one of each other. Placing the missing replicas on
+// the least loaded brokers is not always enough, when the partitions still
+// missing replicas already have them. As the most loaded broker has more
+// replicas, some partition has it and not the least loaded one. Of those, the
+// partition where the change adds the fewest moves is picked. It returns an
+// error if there is no such partition, which only happens when a partition
+// has the same broker twice
+func balanceReplicas(current PartitionReplicasList, proposed PartitionReplicasList, replicas *brokerShares) error {
+	for {
+		most, fewest := replicas.extremes()
+		if replicas.count[most]-replicas.count[fewest] <= 1 {
+			return nil
+		}
+
+		pick, pickCost := -1, 0
+		for i := range proposed {
+			if !containsBrokerID(proposed[i].Replicas, most) || containsBrokerID(proposed[i].Replicas, fewest) {
+				continue
+			}
+			cost := 0
+			if !containsBrokerID(current[i].Replicas, fewest) {
+				cost++
+			}
+			if !containsBrokerID(current[i].Replicas, most) {
+				cost--
+			}
+			if pick == -1 || cost < pickCost {
+				pick, pickCost = i, cost
+			}
+		}
+
+		if pick == -1 {
+			return fmt.Errorf("no partition to move a replica from broker %d to broker %d", most, fewest)
+		}
+
+		for j, id := range proposed[pick].Replicas {
+			if id == most {
+				proposed[pick].Replicas[j] = fewest
+			}
+		}
+		replicas.count[most]--
+		replicas.count[fewest]++
+	}
+}
+
+// balanceLeaders moves preferred leaders away from the brokers leading the
+// most partitions until the brokers are within one of each other. The
+// leadership goes along a chain of partitions: a partition led by the broker
+// is handed to another of its replicas, which hands one of its partitions on,
+// until a broker leading at least two partitions less takes one. Only the
+// first and the last broker of the chain change counts. It stops when no
+// broker has such a chain
+func balanceLeaders(proposed PartitionReplicasList, leader []int32, leaders *brokerShares) {
+	for {
+		moved := false
+		for _, start := range leaders.brokers {
+			if leaders.leaderChain(proposed, leader, start) {
+				moved = true
+				break
+			}
+		}
+		if !moved {
+			return
+		}
+	}
+}
+
+// leaderChain hands one partition led by start on along a chain of partitions,
+// to a broker leading at least two partitions less. It returns false if there
+// is none
+func (s *brokerShares) leaderChain(proposed PartitionReplicasList, leader []int32, start int32) bool {
+	// breadth first search of the brokers the leadership can go to, with the
+	// partition it goes through
+	through := map[int32]int{start: -1}
+	queue := []int32{start}
+	for len(queue) > 0 {
+		from := queue[0]
+		queue = queue[1:]
+
+		for i := range proposed {
+			if leader[i] != from {
+				continue
+			}
+			for _, id := range proposed[i].Replicas {
+				if _, seen := through[id]; seen {
+					continue
+				}
+				through[id] = i
+				queue = append(queue, id)
+
+				if s.count[id] <= s.count[start]-2 {
+					for to := id; to != start; {
+						p := through[to]
+						leader[p], to = to, leader[p]
+					}
+					s.count[start]--
+					s.count[id]++
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// withLeaderFirst moves the leader to the front of the replicas, keeping the
+// order of the others
+func withLeaderFirst(replicas []int32, leader int32) []int32 {
+	ordered := []int32{leader}
+	for _, id := range replicas {
+		if id != leader {
+			ordered = append(ordered, id)
+		}
+	}
+	return ordered
+}
+
+// Changed returns the partitions whose replicas or preferred leader change
+func (p *ReassignmentPlan) Changed() PartitionReplicasList {
+	changed := PartitionReplicasList{}
+	for i := range p.Proposed {
+		if !sameBrokerIDs(p.Current[i].Replicas, p.Proposed[i].Replicas) {
+			changed = append(changed, p.Proposed[i])
+		}
+	}
+	return changed
+}
+
+// Moves returns the number of replicas to copy to a new broker
+func (p *ReassignmentPlan) Moves() int {
+	moves := 0
+	for i := range p.Proposed {
+		for _, id := range p.Proposed[i].Replicas {
+			if !containsBrokerID(p.Current[i].Replicas, id) {
+				moves++
+			}
+		}
+	}
+	return moves
+}
+
+// LeaderChanges returns the number of partitions whose preferred leader
+// changes
+func (p *ReassignmentPlan) LeaderChanges() int {
+	changes := 0
+	for i := range p.Proposed {
+		current, proposed := p.Current[i].Replicas, p.Proposed[i].Replicas
+		if len(current) == 0 || len(proposed) == 0 || current[0] != proposed[0] {
+			changes++
+		}
+	}
+	return changes
+}
+
+// JSON returns the plan in the format of kafka-reassign-partitions, with the
+// partitions that change only
+func (p *ReassignmentPlan) JSON() ([]byte, error) {
+	return json.MarshalIndent(zkPartitionsNode{Version: 1, Partitions: p.Changed()}, "", "  ")
+}
+
+// Lines formats the current and proposed replicas side by side
+func (p *ReassignmentPlan) Lines() []string {
+	lines := []string{
+		fmt.Sprintf("%-40s %-20s %-20s %s", "PARTITION", "CURRENT", "PROPOSED", "MOVES"),
+	}
+
+	for i := range p.Proposed {
+		current, proposed := p.Current[i], p.Proposed[i]
+
+		moves := 0
+		for _, id := range proposed.Replicas {
+			if !containsBrokerID(current.Replicas, id) {
+				moves++
+			}
+		}
+
+		note := ""
+		switch {
+		case moves > 0:
+			note = fmt.Sprintf("%d", moves)
+		case !sameBrokerIDs(current.Replicas, proposed.Replicas):
+			note = "reordered"
+		}
+
+		lines = append(lines, fmt.Sprintf("%-40s %-20s %-20s %s",
+			fmt.Sprintf("%s:%d", proposed.Topic, proposed.Partition),
+			formatBrokerIDs(current.Replicas), formatBrokerIDs(proposed.Replicas), note))
+	}
+
+	lines = append(lines, "", fmt.Sprintf("%d partitions, %d to reassign, %d replica moves, %d preferred leader changes",
+		len(p.Proposed), len(p.Changed()), p.Moves(), p.LeaderChanges()))
+	return lines
+}
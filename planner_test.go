@@ -0,0 +1,144 @@
+package ktop
+
+import (
+	"testing"
+)
+
+// roundRobin lays out the replicas of a topic on the brokers one after the
+// other, the first replica of each partition on the next broker
+func roundRobin(partitions int, replicationFactor int, brokers []int32) PartitionReplicasList {
+	assignments := PartitionReplicasList{}
+	for p := 0; p < partitions; p++ {
+		replicas := []int32{}
+		for r := 0; r < replicationFactor; r++ {
+			replicas = append(replicas, brokers[(p+r)%len(brokers)])
+		}
+		assignments = append(assignments, PartitionReplicas{"t", int32(p), replicas})
+	}
+	return assignments
+}
+
+// spread returns the difference between the highest and the lowest count of
+// the brokers
+func spread(count map[int32]int, brokers []int32) int {
+	min, max := count[brokers[0]], count[brokers[0]]
+	for _, id := range brokers[1:] {
+		if count[id] < min {
+			min = count[id]
+		}
+		if count[id] > max {
+			max = count[id]
+		}
+	}
+	return max - min
+}
+
+func TestPlanReassignment(t *testing.T) {
+	tests := []struct {
+		name    string
+		current PartitionReplicasList
+		brokers []int32
+		moves   int
+	}{
+		{
+			name:    "expand from 3 to 5 brokers",
+			current: roundRobin(12, 2, []int32{1, 2, 3}),
+			brokers: []int32{1, 2, 3, 4, 5},
+			// the new brokers get 5 and 4 of the 24 replicas
+			moves: 9,
+		},
+		{
+			name:    "expand from 3 to 4 brokers, uneven",
+			current: roundRobin(7, 3, []int32{1, 2, 3}),
+			brokers: []int32{1, 2, 3, 4},
+			moves:   5,
+		},
+		{
+			name:    "shrink from 5 to 3 brokers",
+			current: roundRobin(10, 2, []int32{1, 2, 3, 4, 5}),
+			brokers: []int32{1, 2, 3},
+			// only the replicas on brokers 4 and 5 move
+			moves: 8,
+		},
+		{
+			name:    "shrink from 5 to 3 brokers, other IDs",
+			current: roundRobin(9, 3, []int32{1, 2, 3, 4, 5}),
+			brokers: []int32{3, 6, 7},
+			// all the replicas but the 6 on broker 3 move
+			moves: 21,
+		},
+		{
+			name:    "already balanced",
+			current: roundRobin(6, 3, []int32{1, 2, 3}),
+			brokers: []int32{3, 2, 1, 2},
+			moves:   0,
+		},
+		{
+			name: "skewed, where placing the missing replicas is not enough",
+			current: PartitionReplicasList{
+				{"t", 0, []int32{1, 2, 5}}, {"t", 1, []int32{2, 5, 4}}, {"t", 2, []int32{2, 4, 5}},
+				{"t", 3, []int32{3, 2, 5}}, {"t", 4, []int32{5, 2, 1}}, {"t", 5, []int32{3, 1, 2}},
+				{"t", 6, []int32{1, 5, 3}}, {"t", 7, []int32{2, 3, 1}}, {"t", 8, []int32{1, 2, 3}},
+				{"t", 9, []int32{2, 5, 3}}, {"t", 10, []int32{2, 3, 4}}, {"t", 11, []int32{4, 2, 3}},
+				{"t", 12, []int32{4, 5, 3}}, {"t", 13, []int32{1, 4, 5}}, {"t", 14, []int32{1, 3, 5}},
+				{"t", 15, []int32{3, 2, 5}}, {"t", 16, []int32{4, 2, 5}}, {"t", 17, []int32{4, 1, 2}},
+				{"t", 18, []int32{4, 2, 3}}, {"t", 19, []int32{1, 3, 4}}, {"t", 20, []int32{4, 5, 2}},
+			},
+			brokers: []int32{7, 8, 4, 1},
+			// the 10 replicas on broker 1 and the 11 on broker 4 stay
+			moves: 42,
+		},
+	}
+
+	for _, test := range tests {
+		plan, err := PlanReassignment(test.current, test.brokers)
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+
+		replicas := make(map[int32]int)
+		leaders := make(map[int32]int)
+		for i, p := range plan.Proposed {
+			if p.Topic != test.current[i].Topic || p.Partition != test.current[i].Partition {
+				t.Errorf("%s: partition %d is %s:%d, expected %s:%d", test.name, i, p.Topic, p.Partition, test.current[i].Topic, test.current[i].Partition)
+			}
+			if len(p.Replicas) != len(test.current[i].Replicas) {
+				t.Errorf("%s: partition %d has replicas %v, expected %d replicas", test.name, p.Partition, p.Replicas, len(test.current[i].Replicas))
+			}
+			for j, id := range p.Replicas {
+				if !containsBrokerID(plan.Brokers, id) {
+					t.Errorf("%s: partition %d has a replica on broker %d, not a target", test.name, p.Partition, id)
+				}
+				if containsBrokerID(p.Replicas[:j], id) {
+					t.Errorf("%s: partition %d has broker %d twice in %v", test.name, p.Partition, id, p.Replicas)
+				}
+				replicas[id]++
+			}
+			leaders[p.Replicas[0]]++
+		}
+
+		if spread(replicas, plan.Brokers) > 1 {
+			t.Errorf("%s: replicas per broker %v, expected within one of each other", test.name, replicas)
+		}
+		if spread(leaders, plan.Brokers) > 1 {
+			t.Errorf("%s: preferred leaders per broker %v, expected within one of each other", test.name, leaders)
+		}
+		if plan.Moves() != test.moves {
+			t.Errorf("%s: %d replica moves, expected %d", test.name, plan.Moves(), test.moves)
+		}
+	}
+}
+
+func TestPlanReassignmentErrors(t *testing.T) {
+	if _, err := PlanReassignment(roundRobin(3, 2, []int32{1, 2}), nil); err == nil {
+		t.Error("expected an error without target brokers")
+	}
+	if _, err := PlanReassignment(roundRobin(3, 3, []int32{1, 2, 3}), []int32{1, 2}); err == nil {
+		t.Error("expected an error with more replicas than target brokers")
+	}
+	duplicate := PartitionReplicasList{{"t", 0, []int32{1, 1}}, {"t", 1, []int32{1, 2}}}
+	if _, err := PlanReassignment(duplicate, []int32{1, 2, 3}); err == nil {
+		t.Error("expected an error with a broker twice in the replicas")
+	}
+}
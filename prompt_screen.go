@@ -0,0 +1,119 @@
+package ktop
+
+import (
+	"fmt"
+
+	"github.com/nsf/termbox-go"
+)
+
+// PromptScreen asks for a line of text below a preview of what it is about,
+// such as the changes an admin action is about to make. Esc, the left arrow
+// or Ctrl-Q cancel the prompt
+type PromptScreen struct {
+	listCursor
+
+	title  string
+	lines  []string
+	prompt string
+
+	Input string
+
+	// called with the input on Enter. The screen stays up on error, with the
+	// error in the status area
+	onEnter func(screen Screen, input string) error
+}
+
+func NewPromptScreen(title string, lines []string, prompt string, onEnter func(screen Screen, input string) error) *PromptScreen {
+	return &PromptScreen{
+		title:   title,
+		lines:   lines,
+		prompt:  prompt,
+		onEnter: onEnter,
+	}
+}
+
+// NewConfirmScreen asks to type the expected text, such as the name of the
+// topic to delete, before going ahead
+func NewConfirmScreen(title string, lines []string, expect string, onConfirm func(screen Screen) error) *PromptScreen {
+	prompt := fmt.Sprintf("type %q to confirm, or Esc to cancel: ", expect)
+	return NewPromptScreen(title, lines, prompt, func(screen Screen, input string) error {
+		if input != expect {
+			return fmt.Errorf("type %q to confirm", expect)
+		}
+		return onConfirm(screen)
+	})
+}
+
+func (s *PromptScreen) WillShow(screen Screen) error {
+	return nil
+}
+
+// page is the number of preview lines that fit above the prompt
+func (s *PromptScreen) page() int {
+	return h - 5
+}
+
+func (s *PromptScreen) Refresh(screen Screen) {
+	termbox.Clear(coldef, coldef)
+	w, h = termbox.Size()
+
+	screen.Print(s.title, 0, 0, coldef, coldef)
+
+	first, last := s.visible(len(s.lines), s.page())
+	for i := first; i < last; i++ {
+		screen.Print(s.lines[i], 5, i-s.Position+2, coldef, coldef)
+	}
+
+	screen.Print(s.prompt+s.Input, 0, h-3, termbox.ColorYellow, coldef)
+	termbox.SetCursor(len(s.prompt)+len(s.Input), h-3)
+	screen.Flush()
+}
+
+func (s *PromptScreen) OnKeyInput(screen Screen, keyEvent termbox.Event) {
+	_, h = termbox.Size()
+
+	switch keyEvent.Type {
+	case termbox.EventKey:
+		switch keyEvent.Key {
+		case termbox.KeyEsc, termbox.KeyArrowLeft, termbox.KeyCtrlQ:
+			termbox.HideCursor()
+			screen.Pop()
+
+		case termbox.KeyEnter:
+			if err := s.onEnter(screen, s.Input); err != nil {
+				screen.SetError(err)
+				s.Refresh(screen)
+			}
+
+		case termbox.KeyBackspace, termbox.KeyBackspace2:
+			if len(s.Input) > 0 {
+				s.Input = s.Input[0 : len(s.Input)-1]
+			}
+			s.Refresh(screen)
+
+		case termbox.KeySpace:
+			s.Input += " "
+			s.Refresh(screen)
+
+		case termbox.KeyArrowUp, termbox.KeyArrowDown, termbox.KeyPgup, termbox.KeyPgdn:
+			// scroll the preview
+			if len(s.lines) > s.page() {
+				s.Cursor = s.Position
+				if keyEvent.Key == termbox.KeyArrowDown || keyEvent.Key == termbox.KeyPgdn {
+					s.Cursor = s.Position + s.page() - 1
+				}
+				s.onKey(keyEvent.Key, len(s.lines), s.page())
+				s.Refresh(screen)
+			}
+
+		default:
+			if keyEvent.Ch != 0 {
+				s.Input += string(keyEvent.Ch)
+				s.Refresh(screen)
+			}
+		}
+	case termbox.EventError:
+		screen.SetError(keyEvent.Err)
+		screen.Flush()
+	}
+}
@@ -0,0 +1,205 @@
+package ktop
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/nsf/termbox-go"
+	"github.com/samuel/go-zookeeper/zk"
+)
+
+// reassignmentPollInterval is how often the replicas and ISR are read again
+// while waiting for /admin/reassign_partitions to change
+const reassignmentPollInterval = 5 * time.Second
+
+// ReassignmentScreen follows a partition reassignment until all its
+// partitions reach their target replicas
+type ReassignmentScreen struct {
+	listCursor
+
+	targets  PartitionReplicasList
+	progress []ReassignmentProgress
+	counts   map[string]int
+
+	// closed to stop watching, when leaving the screen or once done
+	stop    chan struct{}
+	stopped bool
+
+	cluster *Cluster
+}
+
+func NewReassignmentScreen(cluster *Cluster, targets PartitionReplicasList) *ReassignmentScreen {
+	return &ReassignmentScreen{
+		cluster: cluster,
+		targets: targets,
+	}
+}
+
+// startReassignment asks for a plan file, previews it and submits it after
+// confirmation. It follows the reassignment in progress instead, if any
+func startReassignment(screen Screen, cluster *Cluster) error {
+	pending, err := cluster.PendingReassignments()
+	if err != nil {
+		return err
+	}
+	if len(pending) > 0 {
+		screen.Push(NewReassignmentScreen(cluster, pending))
+		return nil
+	}
+
+	help := []string{
+		"Reassign partitions with a plan in the format of kafka-reassign-partitions,",
+		"such as one written by ktop plan:",
+		"",
+		`    {"version":1,"partitions":[{"topic":"orders","partition":0,"replicas":[4,1,2]}]}`,
+	}
+	screen.Push(NewPromptScreen("Reassign partitions of "+cluster.Name, help, "plan file: ",
+		func(screen Screen, input string) error {
+			targets, err := ReadReassignmentPlan(input)
+			if err != nil {
+				return err
+			}
+			plan, err := previewReassignment(cluster, targets)
+			if err != nil {
+				return err
+			}
+
+			title := fmt.Sprintf("Reassign %d partitions of %s", len(targets), cluster.Name)
+			screen.Replace(NewConfirmScreen(title, plan.Lines(), "reassign", func(screen Screen) error {
+				if err := cluster.ReassignPartitions(targets); err != nil {
+					return err
+				}
+				screen.Replace(NewReassignmentScreen(cluster, targets))
+				return nil
+			}))
+			return nil
+		}))
+	return nil
+}
+
+func (s *ReassignmentScreen) WillShow(screen Screen) error {
+	if s.stop == nil {
+		s.stop = make(chan struct{})
+		go s.watch(screen)
+	}
+
+	progress, err := s.cluster.ReassignmentProgress(s.targets)
+	if err != nil {
+		return err
+	}
+	s.progress = progress
+
+	s.counts = make(map[string]int)
+	for _, p := range s.progress {
+		s.counts[p.State]++
+	}
+	if s.counts[ReassignmentPending] == 0 && s.counts[ReassignmentInProgress] == 0 {
+		s.stopWatching()
+	}
+
+	s.clamp(len(s.progress))
+	return nil
+}
+
+// watch reloads the screen when /admin/reassign_partitions changes, and
+// regularly to follow the ISR of the new replicas
+func (s *ReassignmentScreen) watch(screen Screen) {
+	var watch <-chan zk.Event
+	for {
+		if watch == nil {
+			watch, _ = s.cluster.watchNode(s.cluster.keyBuilder.reassignPartitions())
+		}
+
+		select {
+		case <-watch:
+			watch = nil
+		case <-time.After(reassignmentPollInterval):
+		case <-s.stop:
+			return
+		case <-s.cluster.closed:
+			return
+		}
+
+		screen.Update()
+	}
+}
+
+func (s *ReassignmentScreen) stopWatching() {
+	if !s.stopped {
+		s.stopped = true
+		close(s.stop)
+	}
+}
+
+func (s *ReassignmentScreen) Refresh(screen Screen) {
+	termbox.Clear(coldef, coldef)
+	w, h = termbox.Size()
+
+	summary := fmt.Sprintf("Reassignment of %d partitions: %d pending, %d in progress, %d done",
+		len(s.targets), s.counts[ReassignmentPending], s.counts[ReassignmentInProgress], s.counts[ReassignmentDone])
+	if n := s.counts[ReassignmentNotApplied]; n > 0 {
+		summary += fmt.Sprintf(", %d not applied", n)
+	}
+	screen.Print(summary, 0, 0, coldef, coldef)
+
+	titles := fmt.Sprintf("     %-40s %-12s %-16s %-20s %-16s %s", "PARTITION", "STATE", "TARGET", "REPLICAS", "ISR", "CATCHING UP")
+	screen.Print(titles, 0, 2, coldef, coldef)
+
+	first, last := s.visible(len(s.progress), h-4)
+	for i := first; i < last; i++ {
+		p := s.progress[i]
+
+		fg := coldef
+		catchingUp := ""
+		switch p.State {
+		case ReassignmentInProgress:
+			fg = termbox.ColorYellow
+			catchingUp = formatBrokerIDs(p.CatchingUp())
+		case ReassignmentDone:
+			fg = termbox.ColorGreen
+		case ReassignmentNotApplied:
+			fg = termbox.ColorRed
+		}
+
+		line := fmt.Sprintf("%-40s %-12s %-16s %-20s %-16s %s",
+			fmt.Sprintf("%s:%d", p.Topic, p.Partition), p.State,
+			formatBrokerIDs(p.Target), formatBrokerIDs(p.Replicas), formatBrokerIDs(p.Isr), catchingUp)
+		screen.Print(line, 5, i-s.Position+3, fg, coldef)
+	}
+
+	if len(s.progress) > 0 {
+		screen.Print(" -> ", 0, s.Cursor-s.Position+3, coldef, coldef)
+	}
+
+	termbox.HideCursor()
+	screen.Flush()
+}
+
+func (s *ReassignmentScreen) OnKeyInput(screen Screen, keyEvent termbox.Event) {
+	_, h = termbox.Size()
+
+	switch keyEvent.Type {
+	case termbox.EventKey:
+		switch keyEvent.Key {
+		case termbox.KeyArrowLeft, termbox.KeyCtrlQ:
+			s.stopWatching()
+			screen.Pop()
+
+		case termbox.KeyCtrlR:
+			if err := s.WillShow(screen); err != nil {
+				screen.SetError(err)
+			} else {
+				screen.SetStatus("")
+			}
+			s.Refresh(screen)
+
+		default:
+			if s.onKey(keyEvent.Key, len(s.progress), h-4) {
+				s.Refresh(screen)
+			}
+		}
+	case termbox.EventError:
+		screen.SetError(keyEvent.Err)
+		screen.Flush()
+	}
+}
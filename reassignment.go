@@ -0,0 +1,198 @@
+package ktop
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+)
+
+const (
+	ReassignmentPending    = "pending"
+	ReassignmentInProgress = "in progress"
+	ReassignmentDone       = "done"
+
+	// the partition left /admin/reassign_partitions without reaching its
+	// target, such as when the znode was deleted by hand
+	ReassignmentNotApplied = "not applied"
+)
+
+// ReassignmentProgress is where the reassignment of a partition is at
+type ReassignmentProgress struct {
+	Topic     string
+	Partition int32
+	Target    []int32
+	Replicas  []int32
+	Isr       []int32
+	State     string
+}
+
+// CatchingUp returns the target replicas not in the ISR yet
+func (p ReassignmentProgress) CatchingUp() []int32 {
+	ids := []int32{}
+	for _, id := range p.Target {
+		if !containsBrokerID(p.Isr, id) {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// ReadReassignmentPlan loads a plan in the format of kafka-reassign-partitions
+func ReadReassignmentPlan(path string) (PartitionReplicasList, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	pn := zkPartitionsNode{}
+	if err := json.Unmarshal(data, &pn); err != nil {
+		return nil, fmt.Errorf("invalid plan %s: %v", path, err)
+	}
+	if pn.Version != 1 {
+		return nil, fmt.Errorf("plan %s has version %d, only version 1 is supported", path, pn.Version)
+	}
+	if len(pn.Partitions) == 0 {
+		return nil, fmt.Errorf("plan %s has no partition", path)
+	}
+	listed := make(map[string]bool)
+	for _, p := range pn.Partitions {
+		key := fmt.Sprintf("%s:%d", p.Topic, p.Partition)
+		if listed[key] {
+			return nil, fmt.Errorf("plan %s lists partition %s more than once", path, key)
+		}
+		listed[key] = true
+
+		if len(p.Replicas) == 0 {
+			return nil, fmt.Errorf("plan %s has no replicas for partition %s", path, key)
+		}
+		for i, id := range p.Replicas {
+			if containsBrokerID(p.Replicas[:i], id) {
+				return nil, fmt.Errorf("plan %s has broker %d more than once in the replicas of partition %s", path, id, key)
+			}
+		}
+	}
+
+	sort.Sort(pn.Partitions)
+	return pn.Partitions, nil
+}
+
+// previewReassignment puts the current replicas of the partitions next to
+// their target replicas. It fails if a target replica is not a live broker,
+// as kafka-reassign-partitions does
+func previewReassignment(cluster *Cluster, targets PartitionReplicasList) (*ReassignmentPlan, error) {
+	live := []int32{}
+	for _, b := range cluster.Brokers() {
+		live = append(live, b.ID)
+	}
+	for _, p := range targets {
+		for _, id := range p.Replicas {
+			if !containsBrokerID(live, id) {
+				return nil, fmt.Errorf("broker %d of partition %s:%d is not a live broker, the live brokers are %s",
+					id, p.Topic, p.Partition, strings.TrimSpace(formatBrokerIDs(live)))
+			}
+		}
+	}
+
+	topics := []string{}
+	for i, p := range targets {
+		if i == 0 || targets[i-1].Topic != p.Topic {
+			topics = append(topics, p.Topic)
+		}
+	}
+
+	assignments, err := CurrentAssignments(cluster, topics)
+	if err != nil {
+		return nil, err
+	}
+	current := make(map[string]map[int32][]int32)
+	for _, p := range assignments {
+		if current[p.Topic] == nil {
+			current[p.Topic] = make(map[int32][]int32)
+		}
+		current[p.Topic][p.Partition] = p.Replicas
+	}
+
+	plan := &ReassignmentPlan{Proposed: targets}
+	for _, p := range targets {
+		replicas, ok := current[p.Topic][p.Partition]
+		if !ok {
+			return nil, fmt.Errorf("partition %s:%d does not exist", p.Topic, p.Partition)
+		}
+		plan.Current = append(plan.Current, PartitionReplicas{p.Topic, p.Partition, replicas})
+	}
+	return plan, nil
+}
+
+// ReassignmentProgress compares the replicas and ISR of the partitions in
+// ZooKeeper with their target. The controller first extends the replicas of a
+// partition to the target, then shrinks them to the target once all the new
+// replicas joined the ISR, and removes the partition from
+// /admin/reassign_partitions
+func (c *Cluster) ReassignmentProgress(targets PartitionReplicasList) ([]ReassignmentProgress, error) {
+	if !c.HasZookeeper() {
+		return nil, ErrNoZookeeper
+	}
+	if len(targets) == 0 {
+		return nil, errors.New("no partition to follow")
+	}
+
+	pending, err := c.PendingReassignments()
+	if err != nil {
+		return nil, err
+	}
+	inZnode := make(map[string]bool)
+	for _, p := range pending {
+		inZnode[fmt.Sprintf("%s:%d", p.Topic, p.Partition)] = true
+	}
+
+	assignments := make(map[string]TopicAssignment)
+	states := make(map[string]map[int32]PartitionState)
+
+	progress := []ReassignmentProgress{}
+	for _, p := range targets {
+		if _, ok := assignments[p.Topic]; !ok {
+			if assignments[p.Topic], err = c.Topic(p.Topic); err != nil {
+				return nil, fmt.Errorf("failed to read the assignment of topic %s: %v", p.Topic, err)
+			}
+			if states[p.Topic], err = c.PartitionStates(p.Topic); err != nil {
+				return nil, fmt.Errorf("failed to read the partition states of topic %s: %v", p.Topic, err)
+			}
+		}
+
+		pp := ReassignmentProgress{
+			Topic:     p.Topic,
+			Partition: p.Partition,
+			Target:    p.Replicas,
+			Replicas:  assignments[p.Topic].Replicas[p.Partition],
+			Isr:       states[p.Topic][p.Partition].Isr,
+		}
+
+		switch {
+		case inZnode[fmt.Sprintf("%s:%d", p.Topic, p.Partition)] && containsBrokerIDs(pp.Replicas, pp.Target):
+			pp.State = ReassignmentInProgress
+		case inZnode[fmt.Sprintf("%s:%d", p.Topic, p.Partition)]:
+			pp.State = ReassignmentPending
+		case sameBrokerIDSet(pp.Replicas, pp.Target):
+			pp.State = ReassignmentDone
+		default:
+			pp.State = ReassignmentNotApplied
+		}
+
+		progress = append(progress, pp)
+	}
+
+	return progress, nil
+}
+
+// containsBrokerIDs returns true if all the IDs are in the list
+func containsBrokerIDs(list []int32, ids []int32) bool {
+	for _, id := range ids {
+		if !containsBrokerID(list, id) {
+			return false
+		}
+	}
+	return true
+}
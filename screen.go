@@ -2,6 +2,7 @@ package ktop
 
 import (
 	"log"
+	"sync"
 
 	"github.com/nsf/termbox-go"
 )
@@ -25,6 +26,27 @@ type Screen struct {
 
 	// control channel to stop the loop()
 	stop chan struct{}
+
+	// signaled when the data behind the current context changed
+	updateChan chan struct{}
+
+	// signaled when the brokers or topics of the cluster changed
+	changeChan chan struct{}
+
+	// signaled to draw the current context again, such as after a status
+	// set by a background task
+	redrawChan chan struct{}
+
+	// status area at the bottom of the screen, shared by the copies of the
+	// Screen passed to the contexts
+	status *status
+}
+
+// status is the message shown at the bottom of the screen
+type status struct {
+	lock    sync.Mutex
+	text    string
+	isError bool
 }
 
 type Context interface {
@@ -34,8 +56,17 @@ type Context interface {
 	// Refresh the screen content. This is where the content is printed on the console
 	Refresh(screen Screen)
 
-	// will Show(), called before the screen print the content
-	WillShow(screen Screen)
+	// will Show(), called before the screen print the content. An error is
+	// shown in the status area, and the context is still refreshed
+	WillShow(screen Screen) error
+}
+
+// Updatable is implemented by the contexts that follow the brokers and topics
+// of the cluster as they change. ClusterChanged runs on every broker joining
+// or leaving and every topic created or deleted, so it must be cheap. The
+// other contexts are only reloaded on demand, with Ctrl-R
+type Updatable interface {
+	ClusterChanged(screen Screen) error
 }
 
 func NewScreen(context Context) *Screen {
@@ -50,6 +81,11 @@ func NewScreen(context Context) *Screen {
 		ExitChan:  make(chan bool),
 		stop:      make(chan struct{}),
 		contexts:  []Context{context},
+
+		updateChan: make(chan struct{}, 1),
+		changeChan: make(chan struct{}, 1),
+		redrawChan: make(chan struct{}, 1),
+		status:     &status{},
 	}
 }
 
@@ -81,12 +117,73 @@ func (s *Screen) CurrentContext() Context {
 
 func (s *Screen) Show() {
 	context := s.CurrentContext()
-	context.WillShow(*s)
+	s.willShow(context)
 
 	go s.loop()
 
 	s.refresh()
 	termbox.HideCursor()
+	s.Flush()
+}
+
+// willShow prepares the context, and reports the error in the status area
+func (s *Screen) willShow(context Context) {
+	if err := context.WillShow(*s); err != nil {
+		log.Println("failed to show the screen: " + err.Error())
+		s.SetError(err)
+		return
+	}
+	s.SetStatus("")
+}
+
+// SetError shows the error in the status area until it is cleared
+func (s *Screen) SetError(err error) {
+	s.status.lock.Lock()
+	defer s.status.lock.Unlock()
+
+	s.status.text = "ERROR: " + err.Error()
+	s.status.isError = true
+}
+
+// ClearError removes the error from the status area
+func (s *Screen) ClearError() {
+	s.status.lock.Lock()
+	defer s.status.lock.Unlock()
+
+	if s.status.isError {
+		s.status.text = ""
+		s.status.isError = false
+	}
+}
+
+// SetStatus shows an informational message in the status area
+func (s *Screen) SetStatus(text string) {
+	s.status.lock.Lock()
+	defer s.status.lock.Unlock()
+
+	s.status.text = text
+	s.status.isError = false
+}
+
+// Flush draws the status area on the last line and flushes the screen. The
+// contexts call it instead of termbox.Flush
+func (s *Screen) Flush() {
+	s.status.lock.Lock()
+	text, isError := s.status.text, s.status.isError
+	s.status.lock.Unlock()
+
+	if text != "" {
+		w, h := termbox.Size()
+		fg, bg := coldef, coldef
+		if isError {
+			fg, bg = termbox.ColorWhite, termbox.ColorRed
+		}
+		for x := 0; x < w; x++ {
+			termbox.SetCell(x, h-1, ' ', fg, bg)
+		}
+		s.Print(text, 0, h-1, fg, bg)
+	}
+
 	termbox.Flush()
 }
 
@@ -96,8 +193,11 @@ func (s *Screen) WaitForExit() {
 }
 
 func (s *Screen) Print(text string, col int, row int, fg termbox.Attribute, bg termbox.Attribute) {
-	for i, c := range text {
+	// one cell per rune, not per byte
+	i := 0
+	for _, c := range text {
 		termbox.SetCell(col+i, row, c, fg, bg)
+		i++
 	}
 }
 
@@ -128,6 +228,14 @@ func (s *Screen) loop() {
 func (s *Screen) handleEvents(stopHandler chan struct{}) {
 	log.Println("Start handleEvents")
 
+	// restore the terminal before crashing, otherwise it is left in raw mode
+	defer func() {
+		if r := recover(); r != nil {
+			termbox.Close()
+			panic(r)
+		}
+	}()
+
 	for {
 		context := s.CurrentContext()
 
@@ -137,8 +245,28 @@ func (s *Screen) handleEvents(stopHandler chan struct{}) {
 				context.OnKeyInput(*s, ev)
 			}
 			if ev.Type == termbox.EventError {
-				panic(ev.Err)
+				log.Println("termbox error: " + ev.Err.Error())
+				s.SetError(ev.Err)
+				s.Flush()
+			}
+		case <-s.updateChan:
+			// reload and redraw the current context
+			s.willShow(context)
+			s.refresh()
+			s.Flush()
+		case <-s.changeChan:
+			updatable, ok := context.(Updatable)
+			if !ok {
+				continue
+			}
+			if err := updatable.ClusterChanged(*s); err != nil {
+				log.Println("failed to update the screen: " + err.Error())
+				s.SetError(err)
 			}
+			s.refresh()
+			s.Flush()
+		case <-s.redrawChan:
+			context.Refresh(*s)
 		case <-stopHandler:
 			log.Println("Stopping handleEvents")
 			return
@@ -146,6 +274,36 @@ func (s *Screen) handleEvents(stopHandler chan struct{}) {
 	}
 }
 
+// Update asks the current context to reload its data and redraw. It is safe to
+// call from any goroutine
+func (s *Screen) Update() {
+	select {
+	case s.updateChan <- struct{}{}:
+	default:
+		// an update is already pending
+	}
+}
+
+// Redraw asks the current context to draw again, without reloading its data.
+// It is safe to call from any goroutine
+func (s *Screen) Redraw() {
+	select {
+	case s.redrawChan <- struct{}{}:
+	default:
+		// a redraw is already pending
+	}
+}
+
+// ClusterChanged asks the current context to reload, if it is Updatable. It
+// is safe to call from any goroutine
+func (s *Screen) ClusterChanged() {
+	select {
+	case s.changeChan <- struct{}{}:
+	default:
+		// a change is already pending
+	}
+}
+
 func (s *Screen) Push(context Context) {
 	// call interrupt so that the event loop is not waiting at termbox.PollEvent()
 	// this way the s.stop signal can be picked up
@@ -157,6 +315,15 @@ func (s *Screen) Push(context Context) {
 	s.Show()
 }
 
+// Reset replaces all the screens with the given one, such as when switching
+// to another cluster
+func (s *Screen) Reset(context Context) {
+	termbox.Interrupt()
+	s.stop <- struct{}{}
+	s.contexts = []Context{context}
+	s.Show()
+}
+
 // Pop will close the current screen and goes back to the parent screen
 func (s *Screen) Pop() {
 	termbox.Interrupt()
@@ -164,3 +331,12 @@ func (s *Screen) Pop() {
 	s.contexts = s.contexts[0 : len(s.contexts)-1]
 	s.Show()
 }
+
+// Replace closes the current screen and shows another one in its place, such
+// as a confirmation followed by the progress of what was confirmed
+func (s *Screen) Replace(context Context) {
+	termbox.Interrupt()
+	s.stop <- struct{}{}
+	s.contexts[len(s.contexts)-1] = context
+	s.Show()
+}
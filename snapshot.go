@@ -0,0 +1,287 @@
+package ktop
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/samuel/go-zookeeper/zk"
+)
+
+// SnapshotVersion is the version of the snapshot format. Fields are only
+// added to a version, and always as optional fields. Removing a field or
+// changing its meaning requires a new version
+const SnapshotVersion = 1
+
+// Snapshot is the metadata of a cluster at a point in time. Brokers, topics
+// and partitions are sorted so that two snapshots of the same cluster state
+// are identical
+type Snapshot struct {
+	Version int       `json:"version"`
+	Cluster string    `json:"cluster"`
+	Taken   time.Time `json:"taken"`
+
+	// the bootstrap brokers of a cluster browsed without ZooKeeper, whose
+	// name is not a ZooKeeper connect string
+	Bootstrap []string `json:"bootstrap,omitempty"`
+
+	Brokers []SnapshotBroker `json:"brokers"`
+	Topics  []SnapshotTopic  `json:"topics"`
+}
+
+// SnapshotBroker is the registration of a broker
+type SnapshotBroker struct {
+	ID        int32    `json:"id"`
+	Host      string   `json:"host"`
+	Port      int      `json:"port"`
+	JmxPort   int      `json:"jmx_port"`
+	Endpoints []string `json:"endpoints,omitempty"`
+	Timestamp string   `json:"timestamp,omitempty"`
+	Version   int      `json:"version"`
+}
+
+// SnapshotTopic is a topic with its config overrides and its partitions
+type SnapshotTopic struct {
+	Name       string              `json:"name"`
+	Config     map[string]string   `json:"config,omitempty"`
+	Partitions []SnapshotPartition `json:"partitions"`
+}
+
+// SnapshotPartition is the assignment, leader and ISR of a partition. The
+// replicas are the assignment registered in ZooKeeper when available. The
+// epochs are only set with ZooKeeper, and the offsets only when requested
+type SnapshotPartition struct {
+	ID       int32   `json:"id"`
+	Replicas []int32 `json:"replicas"`
+	Leader   int32   `json:"leader"`
+	Isr      []int32 `json:"isr"`
+
+	LeaderEpoch     *int `json:"leader_epoch,omitempty"`
+	ControllerEpoch *int `json:"controller_epoch,omitempty"`
+
+	LogStartOffset *int64 `json:"log_start_offset,omitempty"`
+	LogEndOffset   *int64 `json:"log_end_offset,omitempty"`
+}
+
+type SnapshotTopics []SnapshotTopic
+
+func (st SnapshotTopics) Len() int {
+	return len(st)
+}
+
+func (st SnapshotTopics) Swap(i, j int) {
+	st[i], st[j] = st[j], st[i]
+}
+
+func (st SnapshotTopics) Less(i, j int) bool {
+	return st[i].Name < st[j].Name
+}
+
+type SnapshotPartitions []SnapshotPartition
+
+func (sp SnapshotPartitions) Len() int {
+	return len(sp)
+}
+
+func (sp SnapshotPartitions) Swap(i, j int) {
+	sp[i], sp[j] = sp[j], sp[i]
+}
+
+func (sp SnapshotPartitions) Less(i, j int) bool {
+	return sp[i].ID < sp[j].ID
+}
+
+// TakeSnapshot reads the metadata of the cluster, and when available the
+// assignments, partition states and configs registered in ZooKeeper. With
+// offsets, the log start and end offset of every partition are included
+func TakeSnapshot(cluster *Cluster, client sarama.Client, offsets bool) (*Snapshot, error) {
+	snapshot := &Snapshot{
+		Version: SnapshotVersion,
+		Cluster: cluster.Name,
+		Taken:   time.Now().UTC(),
+		Brokers: []SnapshotBroker{},
+		Topics:  []SnapshotTopic{},
+	}
+	if !cluster.HasZookeeper() {
+		snapshot.Bootstrap = cluster.SeedBrokers()
+	}
+
+	for _, b := range cluster.Brokers() {
+		snapshot.Brokers = append(snapshot.Brokers, SnapshotBroker{
+			ID:        b.ID,
+			Host:      b.Host,
+			Port:      b.Port,
+			JmxPort:   b.JmxPort,
+			Endpoints: b.Endpoints,
+			Timestamp: b.Timestamp,
+			Version:   b.Version,
+		})
+	}
+
+	metadata, err := fetchMetadata(cluster.SeedBrokers())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the metadata of the cluster: %v", err)
+	}
+
+	partitions := make(map[string][]int32)
+	for _, t := range metadata.Topics {
+		if t.Err != sarama.ErrNoError {
+			log.Println("skip topic " + t.Name + " in the snapshot: " + t.Err.Error())
+			continue
+		}
+
+		topic := SnapshotTopic{
+			Name:       t.Name,
+			Partitions: SnapshotPartitions{},
+		}
+
+		var assignment TopicAssignment
+		var states map[int32]PartitionState
+		if cluster.HasZookeeper() {
+			assignment, err = cluster.Topic(t.Name)
+			if err == nil {
+				states, err = cluster.PartitionStates(t.Name)
+			}
+			if err == nil {
+				topic.Config, err = cluster.TopicConfig(t.Name)
+			}
+			if err == zk.ErrNoNode {
+				// deleted since the metadata request
+				log.Println("skip topic " + t.Name + " in the snapshot: it is gone from ZooKeeper")
+				continue
+			}
+			if err != nil {
+				return nil, fmt.Errorf("failed to read topic %s in ZooKeeper: %v", t.Name, err)
+			}
+		}
+
+		for _, p := range t.Partitions {
+			sp := SnapshotPartition{
+				ID:       p.ID,
+				Replicas: p.Replicas,
+				Leader:   p.Leader,
+				Isr:      p.Isr,
+			}
+			if replicas, ok := assignment.Replicas[p.ID]; ok {
+				sp.Replicas = replicas
+			}
+			if state, ok := states[p.ID]; ok {
+				leaderEpoch, controllerEpoch := state.LeaderEpoch, state.ControllerEpoch
+				sp.LeaderEpoch = &leaderEpoch
+				sp.ControllerEpoch = &controllerEpoch
+			}
+
+			topic.Partitions = append(topic.Partitions, sp)
+			partitions[t.Name] = append(partitions[t.Name], p.ID)
+		}
+		sort.Sort(SnapshotPartitions(topic.Partitions))
+
+		snapshot.Topics = append(snapshot.Topics, topic)
+	}
+	sort.Sort(SnapshotTopics(snapshot.Topics))
+
+	if offsets {
+		start := partitionOffsets(client, partitions, sarama.OffsetOldest)
+		end := partitionOffsets(client, partitions, sarama.OffsetNewest)
+
+		for _, t := range snapshot.Topics {
+			for i := range t.Partitions {
+				p := &t.Partitions[i]
+				if offset, ok := start[t.Name][p.ID]; ok {
+					p.LogStartOffset = &offset
+				}
+				if offset, ok := end[t.Name][p.ID]; ok {
+					p.LogEndOffset = &offset
+				}
+			}
+		}
+	}
+
+	return snapshot, nil
+}
+
+// Write saves the snapshot as indented JSON
+func (s *Snapshot) Write(path string) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// ReadSnapshot loads a snapshot written by Snapshot.Write
+func ReadSnapshot(path string) (*Snapshot, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot := &Snapshot{}
+	if err := json.Unmarshal(data, snapshot); err != nil {
+		return nil, fmt.Errorf("invalid snapshot %s: %v", path, err)
+	}
+	if snapshot.Version != SnapshotVersion {
+		return nil, fmt.Errorf("snapshot %s has version %d, only version %d is supported", path, snapshot.Version, SnapshotVersion)
+	}
+	return snapshot, nil
+}
+
+// snapshotTimeFormat is the time of a snapshot in its default file name. It
+// sorts chronologically
+const snapshotTimeFormat = "20060102-150405"
+
+// SnapshotFileName returns the default file name of a snapshot of the cluster
+func SnapshotFileName(cluster string, taken time.Time) string {
+	return fmt.Sprintf("ktop-snapshot-%s-%s.json", snapshotFileCluster(cluster), taken.Format(snapshotTimeFormat))
+}
+
+// snapshotFileCluster escapes the name of a cluster for a file name. Letters,
+// digits, '-', '_' and '.' are kept, the other bytes are escaped as %XX, so
+// that two clusters never share a file name
+func snapshotFileCluster(cluster string) string {
+	name := ""
+	for i := 0; i < len(cluster); i++ {
+		c := cluster[i]
+		if c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_' || c == '.' {
+			name += string(c)
+		} else {
+			name += fmt.Sprintf("%%%02X", c)
+		}
+	}
+	return name
+}
+
+// LiveSnapshot connects to the cluster and takes its snapshot
+func LiveSnapshot(cc ClusterConfig, offsets bool) (*Snapshot, error) {
+	cluster, err := cc.Connect()
+	if err != nil {
+		return nil, err
+	}
+	defer cluster.Close()
+
+	client, err := sarama.NewClient(cluster.SeedBrokers(), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+
+	return TakeSnapshot(cluster, client, offsets)
+}
+
+// SaveSnapshot connects to the cluster and writes its snapshot. An empty path
+// uses SnapshotFileName in the current directory. It returns the path written
+func SaveSnapshot(cc ClusterConfig, path string, offsets bool) (string, error) {
+	snapshot, err := LiveSnapshot(cc, offsets)
+	if err != nil {
+		return "", err
+	}
+
+	if path == "" {
+		path = SnapshotFileName(snapshot.Cluster, snapshot.Taken)
+	}
+	return path, snapshot.Write(path)
+}
@@ -0,0 +1,127 @@
+package ktop
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/nsf/termbox-go"
+)
+
+// StatusScreen shows the controller of the cluster, and the admin operations
+// in flight: partition reassignments, preferred replica elections and topic
+// deletions
+type StatusScreen struct {
+	listCursor
+
+	lines []string
+
+	client  sarama.Client
+	cluster *Cluster
+	broker  string
+}
+
+func NewStatusScreen(cluster *Cluster, client sarama.Client, broker string) *StatusScreen {
+	return &StatusScreen{
+		cluster: cluster,
+		client:  client,
+		broker:  broker,
+	}
+}
+
+func (s *StatusScreen) WillShow(screen Screen) error {
+	s.lines = []string{"Cluster: " + s.cluster.Name}
+	defer func() {
+		s.clamp(len(s.lines))
+	}()
+
+	// the admin operations matter most during a controller election, list
+	// them without a controller as well
+	controller, err := s.cluster.Controller()
+	switch err {
+	case nil:
+		s.lines = append(s.lines,
+			fmt.Sprintf("Controller: broker %d (%s), elected %s, %s ago",
+				controller.BrokerID, s.cluster.Broker(strconv.Itoa(int(controller.BrokerID))),
+				controller.Elected.Format("2006-01-02 15:04:05"), formatDuration(time.Since(controller.Elected))),
+			fmt.Sprintf("Controller epoch: %d", controller.Epoch),
+			"")
+	case ErrNoController:
+		s.lines = append(s.lines, "Controller: none (election in progress)", "")
+	default:
+		return err
+	}
+
+	reassignments, err := s.cluster.PendingReassignments()
+	if err != nil {
+		return err
+	}
+	s.lines = append(s.lines, fmt.Sprintf("Partition reassignments in progress: %d", len(reassignments)))
+	for _, p := range reassignments {
+		s.lines = append(s.lines, fmt.Sprintf("    %s:%d -> %s", p.Topic, p.Partition, formatBrokerIDs(p.Replicas)))
+	}
+	s.lines = append(s.lines, "")
+
+	elections, err := s.cluster.PendingPreferredReplicaElections()
+	if err != nil {
+		return err
+	}
+	s.lines = append(s.lines, fmt.Sprintf("Preferred replica elections in progress: %d", len(elections)))
+	for _, p := range elections {
+		s.lines = append(s.lines, fmt.Sprintf("    %s:%d", p.Topic, p.Partition))
+	}
+	s.lines = append(s.lines, "")
+
+	deletions, err := s.cluster.PendingTopicDeletions()
+	if err != nil {
+		return err
+	}
+	s.lines = append(s.lines, fmt.Sprintf("Topic deletions in progress: %d", len(deletions)))
+	for _, topic := range deletions {
+		s.lines = append(s.lines, "    "+topic)
+	}
+
+	return nil
+}
+
+func (s *StatusScreen) Refresh(screen Screen) {
+	termbox.Clear(coldef, coldef)
+	w, h = termbox.Size()
+
+	first, last := s.visible(len(s.lines), h-1)
+	for i := first; i < last; i++ {
+		screen.Print(s.lines[i], 0, i-s.Position, coldef, coldef)
+	}
+
+	termbox.HideCursor()
+	screen.Flush()
+}
+
+func (s *StatusScreen) OnKeyInput(screen Screen, keyEvent termbox.Event) {
+	_, h = termbox.Size()
+
+	switch keyEvent.Type {
+	case termbox.EventKey:
+		switch keyEvent.Key {
+		case termbox.KeyArrowLeft, termbox.KeyCtrlQ:
+			screen.Pop()
+
+		case termbox.KeyCtrlR:
+			if err := s.WillShow(screen); err != nil {
+				screen.SetError(err)
+			} else {
+				screen.SetStatus("")
+			}
+			s.Refresh(screen)
+
+		default:
+			if s.onKey(keyEvent.Key, len(s.lines), h-1) {
+				s.Refresh(screen)
+			}
+		}
+	case termbox.EventError:
+		screen.SetError(keyEvent.Err)
+		screen.Flush()
+	}
+}
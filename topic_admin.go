@@ -0,0 +1,271 @@
+package ktop
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/samuel/go-zookeeper/zk"
+)
+
+// maxTopicNameLength is the longest topic name Kafka accepts
+const maxTopicNameLength = 249
+
+// ValidateTopicName checks the name against the rules of Kafka: only ASCII
+// letters, digits, '.', '_' and '-', at most maxTopicNameLength characters
+func ValidateTopicName(name string) error {
+	switch {
+	case name == "":
+		return errors.New("the topic name is empty")
+	case name == "." || name == "..":
+		return fmt.Errorf("%q is not a legal topic name", name)
+	case len(name) > maxTopicNameLength:
+		return fmt.Errorf("the topic name is %d characters long, more than %d", len(name), maxTopicNameLength)
+	}
+
+	for _, c := range name {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '.' || c == '_' || c == '-') {
+			return fmt.Errorf("illegal character %q in topic name, only letters, digits, '.', '_' and '-' are legal", c)
+		}
+	}
+	return nil
+}
+
+// topicCollisions returns the topics whose name only differs from the given
+// one by '.' and '_'. Their metric names collide in Kafka
+func topicCollisions(name string, topics []string) []string {
+	key := strings.Replace(name, ".", "_", -1)
+
+	collisions := []string{}
+	for _, t := range topics {
+		if t != name && strings.Replace(t, ".", "_", -1) == key {
+			collisions = append(collisions, t)
+		}
+	}
+	sort.Strings(collisions)
+	return collisions
+}
+
+// ParseConfigOverrides parses comma separated key=value topic configs, and
+// checks them against the topic-level configs
+func ParseConfigOverrides(text string) (map[string]string, error) {
+	config := make(map[string]string)
+	for _, field := range strings.Split(text, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+
+		kv := strings.SplitN(field, "=", 2)
+		if len(kv) != 2 || strings.TrimSpace(kv[0]) == "" {
+			return nil, fmt.Errorf("invalid config %q, expected key=value", field)
+		}
+		key, value := strings.TrimSpace(kv[0]), strings.TrimSpace(kv[1])
+
+		if err := ValidateTopicConfig(key, value); err != nil {
+			return nil, err
+		}
+		config[key] = value
+	}
+	return config, nil
+}
+
+// continueIndex is the startIndex of assignReplicas when adding partitions to
+// a topic: the first of the sorted brokers at or after the first replica of
+// partition 0, as Kafka does, even if that replica is not live. It wraps
+// around to the first broker when all the brokers are before it
+func continueIndex(brokers []int32, head int32) int {
+	for i, id := range brokers {
+		if id >= head {
+			return i
+		}
+	}
+	return 0
+}
+
+// assignReplicas spreads the replicas of new partitions on the brokers, the
+// way Kafka does without racks. The first replica of each partition goes
+// round robin from startIndex, and the other replicas follow it with a shift
+// that grows each time the first replicas wrap around the brokers. A negative
+// startIndex or shift is picked at random, as Kafka does for a new topic
+func assignReplicas(brokers []int32, firstPartition int32, partitions int, replicationFactor int, startIndex int, shift int) (map[int32][]int32, error) {
+	if partitions <= 0 {
+		return nil, errors.New("the number of partitions must be positive")
+	}
+	if replicationFactor <= 0 {
+		return nil, errors.New("the replication factor must be positive")
+	}
+	if replicationFactor > len(brokers) {
+		return nil, fmt.Errorf("replication factor %d is larger than the %d live brokers", replicationFactor, len(brokers))
+	}
+
+	n := len(brokers)
+	random := rand.New(rand.NewSource(time.Now().UnixNano()))
+	if startIndex < 0 {
+		startIndex = random.Intn(n)
+	}
+	if shift < 0 {
+		shift = random.Intn(n)
+	}
+
+	assignment := make(map[int32][]int32)
+	for i := 0; i < partitions; i++ {
+		partition := firstPartition + int32(i)
+		if partition > 0 && int(partition)%n == 0 {
+			shift++
+		}
+
+		first := (int(partition) + startIndex) % n
+		replicas := []int32{brokers[first]}
+		for j := 0; j < replicationFactor-1; j++ {
+			replicas = append(replicas, brokers[(first+1+(shift+j)%(n-1))%n])
+		}
+		assignment[partition] = replicas
+	}
+	return assignment, nil
+}
+
+// keysRemapped estimates the fraction of keys a hash partitioner sends to
+// another partition when a topic grows from n to m partitions. For uniformly
+// distributed hashes, a key stays when hash%n == hash%m, which holds for
+// min(n, m) out of every lcm(n, m) hashes
+func keysRemapped(n int, m int) float64 {
+	if n <= 0 || m <= 0 || n == m {
+		return 0
+	}
+
+	a, b := n, m
+	for b != 0 {
+		a, b = b, a%b
+	}
+	lcm := float64(n) / float64(a) * float64(m)
+
+	min := n
+	if m < n {
+		min = m
+	}
+	return 1 - float64(min)/lcm
+}
+
+// assignmentLines formats the replicas of partitions for a preview, followed by
+// the number of replicas and preferred leaders each broker gets
+func assignmentLines(assignment map[int32][]int32) []string {
+	ids := make(PartitionIDs, 0, len(assignment))
+	for id := range assignment {
+		ids = append(ids, id)
+	}
+	sort.Sort(ids)
+
+	replicas := make(map[int32]int)
+	leaders := make(map[int32]int)
+	lines := []string{}
+	for _, id := range ids {
+		lines = append(lines, fmt.Sprintf("    partition %-6d replicas: %s", id, formatBrokerIDs(assignment[id])))
+		for i, b := range assignment[id] {
+			replicas[b]++
+			if i == 0 {
+				leaders[b]++
+			}
+		}
+	}
+
+	brokers := make(PartitionIDs, 0, len(replicas))
+	for b := range replicas {
+		brokers = append(brokers, b)
+	}
+	sort.Sort(brokers)
+
+	lines = append(lines, "")
+	for _, b := range brokers {
+		lines = append(lines, fmt.Sprintf("    broker %-6d %d replicas, preferred leader of %d", b, replicas[b], leaders[b]))
+	}
+	return lines
+}
+
+// CreateTopic registers a new topic with its replica assignment and config
+// overrides, the way kafka-topics --create does. The config is written first,
+// so that the brokers find it when they create the partitions
+func (c *Cluster) CreateTopic(name string, assignment map[int32][]int32, config map[string]string) error {
+	if !c.HasZookeeper() {
+		return ErrNoZookeeper
+	}
+	if err := ValidateTopicName(name); err != nil {
+		return err
+	}
+
+	exists, _, err := c.zkconn.Exists(c.keyBuilder.topic(name))
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %v", c.keyBuilder.topic(name), err)
+	}
+	if exists {
+		return errors.New("topic " + name + " already exists")
+	}
+
+	if config == nil {
+		config = make(map[string]string)
+	}
+	data, err := json.Marshal(zkTopicConfigNode{Version: 1, Config: config})
+	if err != nil {
+		return err
+	}
+	err = c.createNode(c.keyBuilder.topicConfig(name), data)
+	if err == zk.ErrNodeExists {
+		// left over from a deleted topic
+		_, err = c.zkconn.Set(c.keyBuilder.topicConfig(name), data, -1)
+	}
+	if err != nil {
+		return err
+	}
+
+	tn := zkTopicNode{Version: 1, Partitions: make(map[string][]int32)}
+	for id, replicas := range assignment {
+		tn.Partitions[strconv.Itoa(int(id))] = replicas
+	}
+	if data, err = json.Marshal(tn); err != nil {
+		return err
+	}
+	err = c.createNode(c.keyBuilder.topic(name), data)
+	if err == zk.ErrNodeExists {
+		return errors.New("topic " + name + " already exists")
+	}
+	return err
+}
+
+// AddPartitions adds partitions to a topic with their replica assignment, the
+// way kafka-topics --alter --partitions does. It fails if the assignment of
+// the topic changed since it was read
+func (c *Cluster) AddPartitions(topic string, assignment TopicAssignment, added map[int32][]int32) error {
+	if !c.HasZookeeper() {
+		return ErrNoZookeeper
+	}
+
+	tn := zkTopicNode{Version: 1, Partitions: make(map[string][]int32)}
+	for id, replicas := range assignment.Replicas {
+		tn.Partitions[strconv.Itoa(int(id))] = replicas
+	}
+	for id, replicas := range added {
+		if _, ok := assignment.Replicas[id]; ok {
+			return fmt.Errorf("partition %d of topic %s already exists", id, topic)
+		}
+		tn.Partitions[strconv.Itoa(int(id))] = replicas
+	}
+
+	data, err := json.Marshal(tn)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.zkconn.Set(c.keyBuilder.topic(topic), data, assignment.Version)
+	if err == zk.ErrBadVersion {
+		return errors.New("the assignment of topic " + topic + " changed meanwhile, try again")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update %s: %v", c.keyBuilder.topic(topic), err)
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package ktop
+
+import (
+	"testing"
+)
+
+func TestContinueIndex(t *testing.T) {
+	tests := []struct {
+		name    string
+		brokers []int32
+		head    int32
+		index   int
+	}{
+		{"head is live", []int32{1, 2, 3}, 2, 1},
+		{"head is not live", []int32{1, 3, 5}, 4, 2},
+		{"head is the first broker", []int32{1, 2, 3}, 1, 0},
+		{"all the brokers are before the head", []int32{1, 2, 3}, 7, 0},
+	}
+
+	for _, test := range tests {
+		if index := continueIndex(test.brokers, test.head); index != test.index {
+			t.Errorf("%s: index %d, expected %d", test.name, index, test.index)
+		}
+	}
+}
+
+func TestAssignReplicasWrapAround(t *testing.T) {
+	// the first replica of partition 0 was on broker 7, which is gone: the
+	// first replica of partition p goes on the broker p % 3 from the start
+	brokers := []int32{1, 2, 3}
+	start := continueIndex(brokers, 7)
+	assignment, err := assignReplicas(brokers, 3, 3, 2, start, start)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, id := range brokers {
+		replicas := assignment[int32(3+i)]
+		if len(replicas) != 2 || replicas[0] != id {
+			t.Errorf("partition %d has replicas %v, expected 2 replicas led by broker %d", 3+i, replicas, id)
+		}
+	}
+}
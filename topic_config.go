@@ -0,0 +1,380 @@
+package ktop
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/samuel/go-zookeeper/zk"
+)
+
+// zkTopicConfigNode is the content of /config/topics/<topic>
+type zkTopicConfigNode struct {
+	Version int               `json:"version"`
+	Config  map[string]string `json:"config"`
+}
+
+// configChangePrefix is the name of the sequential config change
+// notifications, before their sequence number
+const configChangePrefix = "config_change_"
+
+// zkConfigChangeNode is the content of a /config/changes notification written
+// by Kafka 0.9 and later. Kafka 0.8 writes the topic name as a JSON string
+type zkConfigChangeNode struct {
+	Version    int    `json:"version"`
+	EntityType string `json:"entity_type"`
+	EntityName string `json:"entity_name"`
+}
+
+// ConfigChange is a config change notification not yet purged by the brokers
+type ConfigChange struct {
+	Name    string
+	Topic   string
+	Created time.Time
+}
+
+// TopicConfigKey is a topic-level config, the broker config that provides its
+// default value, and the type of its value
+type TopicConfigKey struct {
+	Name       string
+	BrokerName string
+	Type       ConfigType
+}
+
+// TopicConfigKeys are the topic-level configs of Kafka
+var TopicConfigKeys = []TopicConfigKey{
+	{"cleanup.policy", "log.cleanup.policy", oneOf("delete", "compact")},
+	{"compression.type", "compression.type", oneOf("uncompressed", "snappy", "lz4", "gzip", "producer")},
+	{"delete.retention.ms", "log.cleaner.delete.retention.ms", long(0)},
+	{"file.delete.delay.ms", "log.segment.delete.delay.ms", long(0)},
+	{"flush.messages", "log.flush.interval.messages", long(0)},
+	{"flush.ms", "log.flush.interval.ms", long(0)},
+	{"index.interval.bytes", "log.index.interval.bytes", integer(0)},
+	{"max.message.bytes", "message.max.bytes", integer(0)},
+	{"min.cleanable.dirty.ratio", "log.cleaner.min.cleanable.ratio", double(0, 1)},
+	{"min.insync.replicas", "min.insync.replicas", integer(1)},
+	{"retention.bytes", "log.retention.bytes", long(-1)},
+	{"retention.ms", "log.retention.ms", long(-1)},
+	{"segment.bytes", "log.segment.bytes", integer(14)},
+	{"segment.index.bytes", "log.index.size.max.bytes", integer(0)},
+	{"segment.jitter.ms", "log.roll.jitter.ms", long(0)},
+	{"segment.ms", "log.roll.ms", long(0)},
+	{"unclean.leader.election.enable", "unclean.leader.election.enable", boolean()},
+}
+
+// ConfigType is the type of a config value, with the values Kafka accepts
+type ConfigType struct {
+	Kind string
+
+	// range of the numeric values
+	Min float64
+	Max float64
+
+	// accepted values of a string
+	Values []string
+}
+
+func long(min int64) ConfigType {
+	return ConfigType{Kind: "long", Min: float64(min), Max: math.MaxInt64}
+}
+
+func integer(min int64) ConfigType {
+	return ConfigType{Kind: "int", Min: float64(min), Max: math.MaxInt32}
+}
+
+func double(min float64, max float64) ConfigType {
+	return ConfigType{Kind: "double", Min: min, Max: max}
+}
+
+func boolean() ConfigType {
+	return ConfigType{Kind: "boolean", Values: []string{"true", "false"}}
+}
+
+func oneOf(values ...string) ConfigType {
+	return ConfigType{Kind: "string", Values: values}
+}
+
+// String describes the type and its accepted values
+func (t ConfigType) String() string {
+	switch t.Kind {
+	case "long", "int":
+		return fmt.Sprintf("%s >= %v", t.Kind, t.Min)
+	case "double":
+		return fmt.Sprintf("%s in [%v, %v]", t.Kind, t.Min, t.Max)
+	}
+	return strings.Join(t.Values, "|")
+}
+
+// Validate checks the value is of the type and within its accepted values
+func (t ConfigType) Validate(value string) error {
+	switch t.Kind {
+	case "long", "int":
+		bits := 64
+		if t.Kind == "int" {
+			bits = 32
+		}
+		v, err := strconv.ParseInt(value, 10, bits)
+		if err != nil {
+			return fmt.Errorf("%q is not a valid %s", value, t.Kind)
+		}
+		if float64(v) < t.Min {
+			return fmt.Errorf("%d is less than %v", v, t.Min)
+		}
+
+	case "double":
+		v, err := strconv.ParseFloat(value, 64)
+		// NaN is not within any range, but compares false with its bounds
+		if err != nil || math.IsNaN(v) {
+			return fmt.Errorf("%q is not a valid %s", value, t.Kind)
+		}
+		if v < t.Min || v > t.Max {
+			return fmt.Errorf("%v is not in [%v, %v]", v, t.Min, t.Max)
+		}
+
+	case "boolean":
+		// Kafka ignores the case of booleans
+		if !strings.EqualFold(value, "true") && !strings.EqualFold(value, "false") {
+			return fmt.Errorf("%q is not one of %s", value, strings.Join(t.Values, ", "))
+		}
+
+	default:
+		for _, allowed := range t.Values {
+			if value == allowed {
+				return nil
+			}
+		}
+		return fmt.Errorf("%q is not one of %s", value, strings.Join(t.Values, ", "))
+	}
+	return nil
+}
+
+// topicConfigKey returns the topic-level config with the given name
+func topicConfigKey(name string) (TopicConfigKey, bool) {
+	for _, key := range TopicConfigKeys {
+		if key.Name == name {
+			return key, true
+		}
+	}
+	return TopicConfigKey{}, false
+}
+
+// ValidateTopicConfig checks the key is a topic-level config, and the value
+// is valid for it
+func ValidateTopicConfig(key string, value string) error {
+	k, ok := topicConfigKey(key)
+	if !ok {
+		return fmt.Errorf("unknown topic config %s", key)
+	}
+	if err := k.Type.Validate(value); err != nil {
+		return fmt.Errorf("invalid value for %s: %v", key, err)
+	}
+	return nil
+}
+
+// LoadBrokerDefaults reads a broker server.properties file, and returns the
+// default value of the topic-level configs it sets, keyed by topic config name
+func LoadBrokerDefaults(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	props := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "!") {
+			continue
+		}
+
+		i := strings.IndexAny(line, "=:")
+		if i < 0 {
+			continue
+		}
+		props[strings.TrimSpace(line[:i])] = strings.TrimSpace(line[i+1:])
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	// the broker can set the retention and the roll time in hours or
+	// minutes, while the topic config is in milliseconds
+	fromUnit(props, "log.retention.ms", "log.retention.minutes", 60*1000)
+	fromUnit(props, "log.retention.ms", "log.retention.hours", 60*60*1000)
+	fromUnit(props, "log.roll.ms", "log.roll.hours", 60*60*1000)
+	fromUnit(props, "log.roll.jitter.ms", "log.roll.jitter.hours", 60*60*1000)
+
+	defaults := make(map[string]string)
+	for _, key := range TopicConfigKeys {
+		if v, ok := props[key.BrokerName]; ok {
+			defaults[key.Name] = v
+		}
+	}
+	return defaults, nil
+}
+
+func fromUnit(props map[string]string, key string, unitKey string, factor int64) {
+	if _, ok := props[key]; ok {
+		return
+	}
+	v, err := strconv.ParseInt(props[unitKey], 10, 64)
+	if err != nil {
+		return
+	}
+	props[key] = strconv.FormatInt(v*factor, 10)
+}
+
+// TopicConfig reads the config overrides of a topic. It is empty if the topic
+// uses the broker defaults
+func (c *Cluster) TopicConfig(topic string) (map[string]string, error) {
+	config, _, err := c.topicConfigVersion(topic)
+	return config, err
+}
+
+// topicConfigVersion reads the config overrides of a topic, and the version of
+// its config znode, or -1 if the topic has none
+func (c *Cluster) topicConfigVersion(topic string) (map[string]string, int32, error) {
+	if !c.HasZookeeper() {
+		return nil, -1, ErrNoZookeeper
+	}
+
+	data, stat, err := c.zkconn.Get(c.keyBuilder.topicConfig(topic))
+	if err == zk.ErrNoNode {
+		return map[string]string{}, -1, nil
+	}
+	if err != nil {
+		return nil, -1, err
+	}
+
+	cn := zkTopicConfigNode{}
+	if err := json.Unmarshal(data, &cn); err != nil {
+		return nil, -1, fmt.Errorf("invalid config for topic %s: %v", topic, err)
+	}
+	if cn.Config == nil {
+		cn.Config = map[string]string{}
+	}
+	return cn.Config, stat.Version, nil
+}
+
+// SetTopicConfig replaces the config overrides of a topic and notifies the
+// brokers, the way kafka-topics --alter --config does. version is the version
+// of the config znode the overrides were read from, -1 if there was none. It
+// fails if the config changed since
+func (c *Cluster) SetTopicConfig(topic string, config map[string]string, version int32) error {
+	if !c.HasZookeeper() {
+		return ErrNoZookeeper
+	}
+
+	data, err := json.Marshal(zkTopicConfigNode{Version: 1, Config: config})
+	if err != nil {
+		return err
+	}
+
+	path := c.keyBuilder.topicConfig(topic)
+	if version < 0 {
+		err = c.createNode(path, data)
+	} else {
+		_, err = c.zkconn.Set(path, data, version)
+	}
+	if err == zk.ErrNodeExists || err == zk.ErrBadVersion || err == zk.ErrNoNode {
+		return errors.New("the config of topic " + topic + " changed meanwhile, try again")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %v", path, err)
+	}
+
+	// the brokers pick up the new config when notified
+	if data, err = c.configChangeData(topic); err != nil {
+		return err
+	}
+	if err := c.createNode(c.keyBuilder.configChanges(), []byte{}); err != nil && err != zk.ErrNodeExists {
+		return err
+	}
+	_, err = c.zkconn.Create(c.keyBuilder.configChange(configChangePrefix), data, zk.FlagSequence, zk.WorldACL(zk.PermAll))
+	if err != nil {
+		return fmt.Errorf("the config of topic %s is written, but the brokers could not be notified: %v", topic, err)
+	}
+	return nil
+}
+
+// configChangeData returns the change notification of a topic in the format
+// of the brokers. Kafka 0.9 and later register brokers with version 2 or more.
+// The 0.8 format is kept until all the live brokers are upgraded, as 0.8
+// brokers cannot parse the 0.9 one during a rolling upgrade
+func (c *Cluster) configChangeData(topic string) ([]byte, error) {
+	brokers := c.Brokers()
+	upgraded := len(brokers) > 0
+	for _, b := range brokers {
+		if b.Version < 2 {
+			upgraded = false
+		}
+	}
+
+	if upgraded {
+		return json.Marshal(zkConfigChangeNode{Version: 1, EntityType: "topics", EntityName: topic})
+	}
+	return json.Marshal(topic)
+}
+
+// TopicConfigChanges returns the config change notifications of a topic that
+// the brokers have not purged yet, oldest first
+func (c *Cluster) TopicConfigChanges(topic string) ([]ConfigChange, error) {
+	if !c.HasZookeeper() {
+		return nil, ErrNoZookeeper
+	}
+
+	names, _, err := c.zkconn.Children(c.keyBuilder.configChanges())
+	if err == zk.ErrNoNode {
+		return []ConfigChange{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// the sequential suffix orders the notifications
+	sort.Strings(names)
+
+	changes := []ConfigChange{}
+	for _, name := range names {
+		data, stat, err := c.zkconn.Get(c.keyBuilder.configChange(name))
+		if err == zk.ErrNoNode {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if configChangeTopic(data) == topic {
+			changes = append(changes, ConfigChange{
+				Name:    name,
+				Topic:   topic,
+				Created: msToTime(stat.Ctime),
+			})
+		}
+	}
+
+	return changes, nil
+}
+
+// configChangeTopic returns the topic of a config change notification, in
+// the format of Kafka 0.8 or 0.9
+func configChangeTopic(data []byte) string {
+	var topic string
+	if err := json.Unmarshal(data, &topic); err == nil {
+		return topic
+	}
+
+	cn := zkConfigChangeNode{}
+	if err := json.Unmarshal(data, &cn); err == nil && cn.EntityType == "topics" {
+		return cn.EntityName
+	}
+	return ""
+}
@@ -7,6 +7,7 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/Shopify/sarama"
 	"github.com/nsf/termbox-go"
@@ -22,6 +23,10 @@ func init() {
 
 const coldef = termbox.ColorDefault
 
+// deletionPollInterval is how often a topic marked for deletion from the
+// topic list is checked
+const deletionPollInterval = 10 * time.Second
+
 var quit = false
 var w, h int
 
@@ -66,13 +71,27 @@ type TopicScreen struct {
 	// map to hold the topic information
 	TopicInfos map[string]TopicInfo
 
+	// offline and under-replicated partitions, counted in the header
+	health ClusterHealth
+
+	// topics marked for deletion, until they are gone from both ZooKeeper
+	// and the metadata
+	deleting map[string]bool
+
 	// query string
 	Query string
 	// filtered
 
 	typeahead *suggest.Suggest
-	client    sarama.Client
-	cluster   *Cluster
+
+	// topics added to the typeahead index
+	indexed map[string]bool
+
+	client  sarama.Client
+	cluster *Cluster
+
+	// session the screen belongs to, to switch to other clusters
+	app *app
 
 	broker string
 }
@@ -82,15 +101,40 @@ func NewTopicScreen(cluster *Cluster, client sarama.Client, broker string) *Topi
 		client:     client,
 		TopicInfos: make(map[string]TopicInfo),
 		typeahead:  suggest.NewSuggest(),
+		indexed:    make(map[string]bool),
+		deleting:   make(map[string]bool),
 		broker:     broker,
 		cluster:    cluster,
 	}
 }
 
 func (s *TopicScreen) refreshTopicIndex() {
+	current := make(map[string]bool, len(s.Topics))
 	for _, t := range s.Topics {
-		if !s.typeahead.ContainsDocument(t) {
+		current[t] = true
+	}
+
+	// the typeahead index cannot remove a topic, so rebuild it when
+	// topics are deleted
+	for t := range s.indexed {
+		if !current[t] {
+			log.Println("rebuild the topic index after topic " + t + " was deleted")
+			s.typeahead = suggest.NewSuggest()
+			s.indexed = make(map[string]bool)
+			break
+		}
+	}
+
+	for t := range s.TopicInfos {
+		if !current[t] {
+			delete(s.TopicInfos, t)
+		}
+	}
+
+	for _, t := range s.Topics {
+		if !s.indexed[t] {
 			s.typeahead.AddSymbol(t)
+			s.indexed[t] = true
 		}
 	}
 }
@@ -104,6 +148,17 @@ func (s *TopicScreen) filter() {
 
 	log.Println("[filter] query:" + s.Query + ", query len: " + strconv.Itoa(len(s.Query)) + ", number of filtered topics: " + strconv.Itoa(len(s.FilteredTopics)))
 
+	// the list may have shrunk under the cursor
+	if s.Cursor >= len(s.FilteredTopics) {
+		s.Cursor = len(s.FilteredTopics) - 1
+	}
+	if s.Cursor < 0 {
+		s.Cursor = 0
+	}
+	if s.Position > s.Cursor {
+		s.Position = s.Cursor
+	}
+
 	s.refreshTopicInformations()
 }
 
@@ -111,7 +166,10 @@ func (s *TopicScreen) refreshTopicInformations() {
 	for _, topic := range s.FilteredTopics {
 		partitions, err := s.client.Partitions(topic)
 		if err != nil {
-			panic(err)
+			// a topic just created in ZooKeeper may not be in the
+			// metadata yet
+			log.Println("failed to get the partitions of topic " + topic + ": " + err.Error())
+			continue
 		}
 
 		if info, ok := s.TopicInfos[topic]; !ok {
@@ -132,10 +190,84 @@ func (s *TopicScreen) refreshTopicInformations() {
 	}
 }
 
-func (s *TopicScreen) WillShow(screen Screen) {
-	s.Topics, _ = s.client.Topics()
+func (s *TopicScreen) WillShow(screen Screen) error {
+	if err := s.reloadTopics(); err != nil {
+		return err
+	}
+
+	metadata, err := fetchMetadata(s.cluster.SeedBrokers())
+	if err != nil {
+		return fmt.Errorf("failed to get the metadata of the cluster: %v", err)
+	}
+	s.health = checkHealth(metadata)
+
+	pending := []string{}
+	if s.cluster.HasZookeeper() {
+		if pending, err = s.cluster.PendingTopicDeletions(); err != nil {
+			log.Println("failed to read the topics marked for deletion: " + err.Error())
+		}
+	}
+	s.refreshDeletions(pending, metadata)
+	return nil
+}
+
+// reloadTopics reloads the topic names, their index and the filtered list
+func (s *TopicScreen) reloadTopics() error {
+	// the topic names watched in ZooKeeper are up to date, while the
+	// client only knows the topics of its last metadata refresh
+	s.Topics = s.cluster.TopicNames()
+	if len(s.Topics) == 0 {
+		topics, err := s.client.Topics()
+		if err != nil {
+			return err
+		}
+		s.Topics = topics
+	}
 	s.refreshTopicIndex()
 	s.filter()
+	return nil
+}
+
+// ClusterChanged reloads the topic names when topics are created or deleted.
+// The health and the deletions pending take the metadata of every topic, and
+// are only reloaded with Ctrl-R
+func (s *TopicScreen) ClusterChanged(screen Screen) error {
+	if err := s.reloadTopics(); err != nil {
+		return err
+	}
+
+	// the topics no longer listed are no longer pending deletion either
+	listed := make(map[string]bool)
+	for _, t := range s.Topics {
+		listed[t] = true
+	}
+	for t := range s.deleting {
+		if !listed[t] {
+			delete(s.deleting, t)
+		}
+	}
+	return nil
+}
+
+// refreshDeletions marks the topics pending deletion, and forgets the deleted
+// topics that are gone from both ZooKeeper and the metadata
+func (s *TopicScreen) refreshDeletions(pending []string, metadata *sarama.MetadataResponse) {
+	present := make(map[string]bool)
+	for _, t := range s.Topics {
+		present[t] = true
+	}
+	for _, t := range metadata.Topics {
+		present[t.Name] = true
+	}
+
+	for _, t := range pending {
+		s.deleting[t] = true
+	}
+	for t := range s.deleting {
+		if !present[t] {
+			delete(s.deleting, t)
+		}
+	}
 }
 
 func (s *TopicScreen) Refresh(screen Screen) {
@@ -149,15 +281,22 @@ func (s *TopicScreen) Refresh(screen Screen) {
 	s.drawContent(screen, w, h)
 
 	termbox.HideCursor()
-	termbox.Flush()
+	screen.Flush()
 }
 
 func (s *TopicScreen) drawHeader(screen Screen) {
 	w, _ = termbox.Size()
 
 	summary := "Number of Topics: " + strconv.Itoa(len(s.Topics))
+	health := fmt.Sprintf(", Under-replicated Partitions: %d, Offline Partitions: %d",
+		len(s.health.UnderReplicated), len(s.health.Offline))
 
 	screen.Print(summary, 0, 0, coldef, coldef)
+	fg := coldef
+	if len(s.health.UnderReplicated) > 0 || len(s.health.Offline) > 0 {
+		fg = termbox.ColorRed
+	}
+	screen.Print(health, len(summary), 0, fg, coldef)
 	screen.Print(s.Query, 0, 1, termbox.ColorBlue, coldef)
 
 	widthForTopic := strconv.Itoa(w - 20)
@@ -182,10 +321,15 @@ func (s *TopicScreen) drawContent(screen Screen, w int, h int) {
 			parts = strconv.Itoa(info.NumPartitions)
 		}
 
+		name, fg := topic, coldef
+		if s.deleting[topic] {
+			name, fg = topic+" (deletion pending)", termbox.ColorRed
+		}
+
 		w := strconv.Itoa(w - 25)
-		line := fmt.Sprintf("%-"+w+"s %16s", topic, parts)
+		line := fmt.Sprintf("%-"+w+"s %16s", name, parts)
 
-		screen.Print(line, 5, i-s.Position+3, coldef, coldef)
+		screen.Print(line, 5, i-s.Position+3, fg, coldef)
 	}
 
 	// draw Cursor
@@ -203,6 +347,10 @@ func (ts *TopicScreen) OnKeyInput(screen Screen, keyEvent termbox.Event) {
 	case termbox.EventKey:
 		switch keyEvent.Key {
 		case termbox.KeyEnter, termbox.KeyArrowRight:
+			if len(ts.FilteredTopics) == 0 {
+				return
+			}
+
 			// navigate to TopicPartition screen
 			topic := ts.FilteredTopics[ts.Cursor]
 			log.Println("Select topic at cursor: " + strconv.Itoa(ts.Cursor) + ", name: " + topic)
@@ -239,7 +387,7 @@ func (ts *TopicScreen) OnKeyInput(screen Screen, keyEvent termbox.Event) {
 			pg := h - 3
 
 			// if there is only one screen, do nothing
-			if len(ts.Topics) <= pg {
+			if len(ts.FilteredTopics) <= pg {
 				return
 			}
 
@@ -248,6 +396,9 @@ func (ts *TopicScreen) OnKeyInput(screen Screen, keyEvent termbox.Event) {
 			if ts.Position >= len(ts.FilteredTopics) {
 				ts.Position = len(ts.FilteredTopics) - (pg / 2)
 			}
+			if ts.Cursor >= len(ts.FilteredTopics) {
+				ts.Cursor = len(ts.FilteredTopics) - 1
+			}
 			log.Println("position after Pgdn:" + strconv.Itoa(ts.Position))
 			ts.Refresh(screen)
 
@@ -265,8 +416,81 @@ func (ts *TopicScreen) OnKeyInput(screen Screen, keyEvent termbox.Event) {
 			if ts.Position < 0 {
 				ts.Position = 0
 			}
+			if ts.Cursor < 0 {
+				ts.Cursor = 0
+			}
 			ts.Refresh(screen)
 
+		case termbox.KeyCtrlG:
+			screen.Push(NewConsumerScreen(ts.cluster, ts.client, ts.broker, ""))
+
+		case termbox.KeyCtrlK:
+			screen.Push(NewBrokerScreen(ts.cluster, ts.client, ts.broker))
+
+		case termbox.KeyCtrlT:
+			screen.Push(NewStatusScreen(ts.cluster, ts.client, ts.broker))
+
+		case termbox.KeyCtrlU:
+			screen.Push(NewHealthScreen(ts.cluster, ts.client, ts.broker))
+
+		case termbox.KeyCtrlL:
+			screen.Push(NewLeaderScreen(ts.cluster, ts.client, ts.broker))
+
+		case termbox.KeyCtrlP:
+			// write a snapshot of the cluster metadata
+			screen.SetStatus("taking a snapshot of " + ts.cluster.Name + "...")
+			screen.Flush()
+			ts.takeSnapshot(screen)
+
+		case termbox.KeyCtrlD:
+			// compare with the latest snapshot
+			screen.Push(NewDiffScreen(ts.cluster, ts.client))
+
+		case termbox.KeyCtrlA:
+			// reassign partitions, or follow the reassignment in progress
+			if err := startReassignment(screen, ts.cluster); err != nil {
+				screen.SetError(err)
+				ts.Refresh(screen)
+			}
+
+		case termbox.KeyCtrlN:
+			if err := createTopic(screen, ts.cluster, ts.Topics); err != nil {
+				screen.SetError(err)
+				ts.Refresh(screen)
+			}
+
+		case termbox.KeyDelete:
+			if ts.Cursor < 0 || ts.Cursor >= len(ts.FilteredTopics) {
+				return
+			}
+			if err := ts.deleteTopic(screen, ts.FilteredTopics[ts.Cursor]); err != nil {
+				screen.SetError(err)
+				ts.Refresh(screen)
+			}
+
+		case termbox.KeyCtrlZ:
+			// browse the ZooKeeper tree of the cluster
+			if !ts.cluster.HasZookeeper() {
+				screen.SetError(ErrNoZookeeper)
+				ts.Refresh(screen)
+				return
+			}
+			screen.Push(NewZnodeScreen(ts.cluster, ts.cluster.Root()))
+
+		case termbox.KeyCtrlR:
+			// reload the health and the deletions pending as well
+			if err := ts.WillShow(screen); err != nil {
+				screen.SetError(err)
+			} else {
+				screen.SetStatus("")
+			}
+			ts.Refresh(screen)
+
+		case termbox.KeyCtrlX:
+			if ts.app != nil {
+				screen.Push(NewClusterScreen(ts.app))
+			}
+
 		case termbox.KeyCtrlQ:
 			screen.ExitChan <- true
 
@@ -289,34 +513,99 @@ func (ts *TopicScreen) OnKeyInput(screen Screen, keyEvent termbox.Event) {
 			}
 		}
 	case termbox.EventError:
-		panic(keyEvent.Err)
+		screen.SetError(keyEvent.Err)
+		screen.Flush()
 	}
 }
 
-func Start(zkstr string) {
-
-	kafkaCluster, err := NewCluster(zkstr)
-	if err != nil {
-		panic(err)
+// deleteTopic asks to type the name of the topic before marking it for
+// deletion, with a warning about the consumer groups that still have offsets
+// for it
+func (ts *TopicScreen) deleteTopic(screen Screen, topic string) error {
+	if !ts.cluster.HasZookeeper() {
+		return ErrNoZookeeper
 	}
 
-	seedBroker := kafkaCluster.SeedBroker()
-
-	log.Println("Seedbroker: " + seedBroker)
+	lines := []string{
+		fmt.Sprintf("Topic %s has %d partitions.", topic, ts.TopicInfos[topic].NumPartitions),
+		"The brokers only delete it with delete.topic.enable=true, otherwise it stays marked for deletion.",
+		"",
+	}
 
-	// initialize logic
-	client, err := sarama.NewClient([]string{seedBroker}, nil)
+	groups, err := ts.cluster.Consumers()
 	if err != nil {
-		fmt.Println(err.Error())
-		return
+		lines = append(lines, "WARNING: failed to read the consumer groups: "+err.Error())
+	} else {
+		readers := []string{}
+		for _, g := range groups {
+			if g.Reads(topic) {
+				readers = append(readers, g.Name)
+			}
+		}
+		sort.Strings(readers)
+
+		if len(readers) > 0 {
+			lines = append(lines, fmt.Sprintf("WARNING: %d consumer groups still have offsets for the topic in ZooKeeper:", len(readers)))
+			for _, name := range readers {
+				lines = append(lines, "    "+name)
+			}
+		} else {
+			lines = append(lines, "No consumer group has offsets for the topic in ZooKeeper.")
+		}
 	}
-	defer client.Close()
+	lines = append(lines, "Offsets committed to Kafka are not checked.")
 
-	content := NewTopicScreen(kafkaCluster, client, seedBroker)
+	screen.Push(NewConfirmScreen("Delete topic "+topic+" from "+ts.cluster.Name, lines, topic, func(screen Screen) error {
+		if err := ts.cluster.DeleteTopic(topic); err != nil {
+			return err
+		}
+		ts.deleting[topic] = true
+		ts.followDeletion(screen, topic)
+
+		screen.Pop()
+		screen.SetStatus("topic " + topic + " marked for deletion")
+		screen.Flush()
+		return nil
+	}))
+	return nil
+}
 
-	topicScreen := NewScreen(content)
+// takeSnapshot writes a snapshot of the cluster in the background, as it
+// reads every topic, and reports where it is written in the status area
+func (ts *TopicScreen) takeSnapshot(screen Screen) {
+	cluster, client := ts.cluster, ts.client
+	go func() {
+		snapshot, err := TakeSnapshot(cluster, client, false)
+		if err == nil {
+			path := SnapshotFileName(snapshot.Cluster, snapshot.Taken)
+			if err = snapshot.Write(path); err == nil {
+				screen.SetStatus("snapshot written to " + path)
+			}
+		}
+		if err != nil {
+			screen.SetError(fmt.Errorf("failed to take a snapshot: %v", err))
+		}
+		screen.Redraw()
+	}()
+}
 
-	log.Println("showing the topic screen now")
-	topicScreen.Show()
-	topicScreen.WaitForExit()
+// followDeletion reloads the screen once the topic is gone from both
+// ZooKeeper and the metadata of the brokers
+func (ts *TopicScreen) followDeletion(screen Screen, topic string) {
+	cluster := ts.cluster
+	go func() {
+		for {
+			select {
+			case <-time.After(deletionPollInterval):
+			case <-cluster.closed:
+				return
+			}
+
+			if cluster.topicGone(topic) {
+				log.Println("topic " + topic + " is deleted")
+				screen.ClusterChanged()
+				return
+			}
+		}
+	}()
 }
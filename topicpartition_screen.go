@@ -1,9 +1,12 @@
 package ktop
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"sort"
+	"strconv"
+	"strings"
 
 	"github.com/Shopify/sarama"
 	"github.com/nsf/termbox-go"
@@ -30,6 +33,11 @@ func (pm PartitionMetadata) Less(i, j int) bool {
 }
 
 type TopicPartitionScreen struct {
+	listCursor
+
+	// partitions selected with space, for a preferred replica election
+	selected map[int32]bool
+
 	topic      string
 	client     sarama.Client
 	cluster    *Cluster
@@ -37,47 +45,74 @@ type TopicPartitionScreen struct {
 	brokers    []*sarama.Broker
 	topics     []*sarama.TopicMetadata
 	partitions PartitionMetadata
+
+	// replica assignment registered in ZooKeeper, to compare against
+	// the replicas reported by the broker metadata
+	assignment TopicAssignment
+
+	// leader and ISR of each partition as written by the controller
+	states map[int32]PartitionState
+	// config overrides of the topic, and the change notifications the
+	// brokers have not processed yet
+	config        map[string]string
+	configChanges []ConfigChange
 }
 
 func NewTopicPartitionScreen(cluster *Cluster, client sarama.Client, topic string, broker string) *TopicPartitionScreen {
 	return &TopicPartitionScreen{
-		client:  client,
-		topic:   topic,
-		broker:  broker,
-		cluster: cluster,
+		client:   client,
+		topic:    topic,
+		broker:   broker,
+		cluster:  cluster,
+		selected: make(map[int32]bool),
 	}
 }
 
-func (s *TopicPartitionScreen) WillShow(screen Screen) {
-	// get TopicPartition metadata
-	broker := sarama.NewBroker(s.broker)
-
-	config := sarama.NewConfig()
+func (s *TopicPartitionScreen) WillShow(screen Screen) error {
+	s.topics = nil
+	s.partitions = nil
 
-	err := broker.Open(config)
+	// get TopicPartition metadata, from the other brokers if the one we
+	// started from is gone
+	metadata, err := fetchMetadata(append([]string{s.broker}, s.cluster.SeedBrokers()...), s.topic)
 	if err != nil {
-		panic(err)
+		return fmt.Errorf("failed to get the metadata of topic %s: %v", s.topic, err)
 	}
-	defer broker.Close()
-
-	// first, obtain meta data about the topic
-	var topics [1]string
-	topics[0] = s.topic
 
-	topicMetaReq := &sarama.MetadataRequest{
-		Topics: topics[:],
+	if len(metadata.Topics) == 0 {
+		return fmt.Errorf("no metadata for topic %s", s.topic)
 	}
-
-	metadata, err := broker.GetMetadata(topicMetaReq)
-	if err != nil {
-		log.Println(err)
-		// panic(err)
+	if metadata.Topics[0].Err != sarama.ErrNoError {
+		// the topic may have been deleted under us
+		return fmt.Errorf("topic %s: %v", s.topic, metadata.Topics[0].Err)
 	}
 
 	s.brokers = metadata.Brokers
 	s.topics = metadata.Topics
 	s.partitions = s.topics[0].Partitions
 	sort.Sort(s.partitions)
+	s.clamp(len(s.partitions))
+
+	s.assignment, err = s.cluster.Topic(s.topic)
+	if err != nil {
+		log.Println("failed to read the assignment of topic " + s.topic + ": " + err.Error())
+	}
+
+	s.states, err = s.cluster.PartitionStates(s.topic)
+	if err != nil {
+		log.Println("failed to read the partition states of topic " + s.topic + ": " + err.Error())
+	}
+
+	s.config, err = s.cluster.TopicConfig(s.topic)
+	if err != nil {
+		log.Println("failed to read the config of topic " + s.topic + ": " + err.Error())
+	}
+
+	s.configChanges, err = s.cluster.TopicConfigChanges(s.topic)
+	if err != nil {
+		log.Println("failed to read the config changes of topic " + s.topic + ": " + err.Error())
+	}
+	return nil
 }
 
 func (s *TopicPartitionScreen) Refresh(screen Screen) {
@@ -85,50 +120,317 @@ func (s *TopicPartitionScreen) Refresh(screen Screen) {
 	// if topic metadata does not exist, do nothing
 	if len(s.topics) == 0 {
 		log.Println("ERROR, topic metadata shouldn't be empty")
+		screen.Flush()
+		return
+	}
+
+	termbox.Clear(coldef, coldef)
+	w, h = termbox.Size()
+
+	header := fmt.Sprintf("%4s%10s%20s%20s%20s%14s%18s%20s  %s",
+		"ID", "Leader", "Replicas", "ISR", "ZK Replicas", "Leader Epoch", "Controller Epoch", "ZK ISR", "Notes")
+	screen.Print(header, 5, 0, coldef, coldef)
+
+	first, last := s.visible(len(s.partitions), h-3)
+	for i := first; i < last; i++ {
+		p := s.partitions[i]
+		replicas := formatBrokerIDs(p.Replicas)
+		isrs := formatBrokerIDs(p.Isr)
+
+		// flag the partitions whose assignment in ZooKeeper differs from
+		// the replicas reported by the broker
+		notes := ""
+		zkReplicas, ok := s.assignment.Replicas[p.ID]
+		if s.assignment.Replicas != nil && (!ok || !sameBrokerIDs(zkReplicas, p.Replicas)) {
+			notes += "replicas differ "
+		}
+
+		// and the partitions whose ISR in ZooKeeper differs from the ISR
+		// reported by the broker, a sign of a controller gone wrong
+		leaderEpoch, controllerEpoch, zkIsr := "", "", ""
+		if state, ok := s.states[p.ID]; ok {
+			leaderEpoch = strconv.Itoa(state.LeaderEpoch)
+			controllerEpoch = strconv.Itoa(state.ControllerEpoch)
+			zkIsr = formatBrokerIDs(state.Isr)
+			if !sameBrokerIDSet(state.Isr, p.Isr) {
+				notes += "ISR differs "
+			}
+		} else if s.states != nil {
+			notes += "no state "
+		}
+
+		fg := coldef
+		if notes != "" {
+			fg = termbox.ColorRed
+		}
+
+		text := fmt.Sprintf("%4v%10v%20s%20s%20s%14s%18s%20s  %s",
+			p.ID, p.Leader, replicas, isrs, formatBrokerIDs(zkReplicas), leaderEpoch, controllerEpoch, zkIsr, notes)
+		screen.Print(text, 5, i-s.Position+1, fg, coldef)
+		if s.selected[p.ID] {
+			screen.Print("*", 4, i-s.Position+1, termbox.ColorYellow, coldef)
+		}
+	}
+
+	if len(s.partitions) > 0 {
+		screen.Print(" -> ", 0, s.Cursor-s.Position+1, coldef, coldef)
+	}
+
+	s.drawConfig(screen, last-first+2)
+
+	termbox.HideCursor()
+	screen.Flush()
+}
+
+// selectedPartitions returns the selected partitions, or the partition under
+// the cursor if none is selected
+func (s *TopicPartitionScreen) selectedPartitions() PartitionMetadata {
+	partitions := PartitionMetadata{}
+	for _, p := range s.partitions {
+		if s.selected[p.ID] {
+			partitions = append(partitions, p)
+		}
+	}
+	if len(partitions) == 0 && len(s.partitions) > 0 {
+		partitions = append(partitions, s.partitions[s.Cursor])
+	}
+	return partitions
+}
+
+// drawConfig prints the config overrides of the topic from the given row.
+// Overrides that differ from the broker defaults are highlighted
+func (s *TopicPartitionScreen) drawConfig(screen Screen, row int) {
+	if s.config == nil {
+		screen.Print("Config overrides: not available", 0, row, coldef, coldef)
 		return
 	}
 
-	topicMetadata := s.topics[0]
-	partitionMetadata := topicMetadata.Partitions
+	title := fmt.Sprintf("Config overrides: %d", len(s.config))
+	if len(s.configChanges) > 0 {
+		last := s.configChanges[len(s.configChanges)-1]
+		title += fmt.Sprintf(", pending change notifications: %d (last %s)",
+			len(s.configChanges), last.Created.Format("2006-01-02 15:04:05"))
+	}
+	screen.Print(title, 0, row, coldef, coldef)
+
+	keys := make([]string, 0, len(s.config))
+	for key := range s.config {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
 
-	header := fmt.Sprintf("%4s%10s%20s%20s", "ID", "Leader", "Replicas", "ISR")
-	screen.Print(header, 0, 0, coldef, coldef)
+	defaults := s.cluster.brokerDefaults
+	for i, key := range keys {
+		text := fmt.Sprintf("    %-32s %-24s", key, s.config[key])
 
-	for r, p := range partitionMetadata {
-		replicas := ""
-		for _, rep := range p.Replicas {
-			replicas += fmt.Sprintf("%v ", rep)
+		fg := coldef
+		if def, ok := defaults[key]; ok && def != s.config[key] {
+			text += " broker default: " + def
+			fg = termbox.ColorYellow
 		}
+		screen.Print(text, 0, row+i+1, fg, coldef)
+	}
+}
+
+func formatBrokerIDs(ids []int32) string {
+	text := ""
+	for _, id := range ids {
+		text += fmt.Sprintf("%v ", id)
+	}
+	return text
+}
+
+// sameBrokerIDs compares two replica lists, including their order since the
+// first replica is the preferred leader
+func sameBrokerIDs(a []int32, b []int32) bool {
+	if len(a) != len(b) {
+		return false
+	}
 
-		isrs := ""
-		for _, isr := range p.Isr {
-			isrs += fmt.Sprintf("%v ", isr)
+	for i := range a {
+		if a[i] != b[i] {
+			return false
 		}
+	}
+	return true
+}
 
-		text := fmt.Sprintf("%4v%10v%20s%20s", p.ID, p.Leader, replicas, isrs)
-		screen.Print(text, 0, r+1, coldef, coldef)
+// sameBrokerIDSet compares two lists of broker IDs regardless of their order,
+// as the ISR is not ordered
+func sameBrokerIDSet(a []int32, b []int32) bool {
+	if len(a) != len(b) {
+		return false
 	}
+
+	for _, id := range a {
+		if !containsBrokerID(b, id) {
+			return false
+		}
+	}
+	return true
 }
 
 func (ts *TopicPartitionScreen) OnKeyInput(screen Screen, keyEvent termbox.Event) {
+	_, h = termbox.Size()
 
 	switch keyEvent.Type {
 	case termbox.EventKey:
 		switch keyEvent.Key {
-		case termbox.KeyEnter:
-		case termbox.KeyArrowDown:
-		case termbox.KeyArrowUp:
 		case termbox.KeyArrowLeft, termbox.KeyCtrlQ:
 			// go up
 			screen.Pop()
 
-		case termbox.KeyCtrlF, termbox.KeyPgdn:
-		case termbox.KeyCtrlB, termbox.KeyPgup:
-		case termbox.KeyBackspace, termbox.KeyBackspace2:
+		case termbox.KeyCtrlG:
+			// consumer groups reading this topic
+			screen.Push(NewConsumerScreen(ts.cluster, ts.client, ts.broker, ts.topic))
+
+		case termbox.KeySpace:
+			// select the partition for a preferred replica election
+			if len(ts.partitions) > 0 {
+				id := ts.partitions[ts.Cursor].ID
+				if ts.selected[id] {
+					delete(ts.selected, id)
+				} else {
+					ts.selected[id] = true
+				}
+				ts.onKey(termbox.KeyArrowDown, len(ts.partitions), h-3)
+				ts.Refresh(screen)
+			}
+
+		case termbox.KeyCtrlE:
+			if err := ts.electPreferredLeaders(screen); err != nil {
+				screen.SetError(err)
+				ts.Refresh(screen)
+			}
+
+		case termbox.KeyCtrlO:
+			// edit the config overrides of the topic
+			if !ts.cluster.HasZookeeper() {
+				screen.SetError(ErrNoZookeeper)
+				ts.Refresh(screen)
+				return
+			}
+			screen.Push(NewConfigScreen(ts.cluster, ts.topic))
+
+		case termbox.KeyCtrlN:
+			if err := ts.addPartitions(screen); err != nil {
+				screen.SetError(err)
+				ts.Refresh(screen)
+			}
+
+		case termbox.KeyCtrlR:
+			if err := ts.WillShow(screen); err != nil {
+				screen.SetError(err)
+			} else {
+				screen.SetStatus("")
+			}
+			ts.Refresh(screen)
 
 		default:
+			if ts.onKey(keyEvent.Key, len(ts.partitions), h-3) {
+				ts.Refresh(screen)
+			}
 		}
 	case termbox.EventError:
-		panic(keyEvent.Err)
+		screen.SetError(keyEvent.Err)
+		screen.Flush()
+	}
+}
+
+// electPreferredLeaders asks to confirm a preferred replica election of the
+// selected partitions not led by their preferred replica, then follows it
+func (ts *TopicPartitionScreen) electPreferredLeaders(screen Screen) error {
+	elections := PartitionReplicasList{}
+	lines := []string{}
+	for _, p := range ts.selectedPartitions() {
+		if len(p.Replicas) == 0 || p.Leader == p.Replicas[0] {
+			continue
+		}
+		elections = append(elections, PartitionReplicas{ts.topic, p.ID, p.Replicas})
+
+		line := fmt.Sprintf("%-40s leader %d -> preferred %d",
+			fmt.Sprintf("%s:%d", ts.topic, p.ID), p.Leader, p.Replicas[0])
+		if !containsBrokerID(p.Isr, p.Replicas[0]) {
+			line += ", not in the ISR: the election will fail"
+		}
+		lines = append(lines, line)
+	}
+	if len(elections) == 0 {
+		return errors.New("the selected partitions are already led by their preferred replica")
+	}
+
+	title := fmt.Sprintf("Move the leader of %d partitions of %s to their preferred replica", len(elections), ts.topic)
+	screen.Push(NewConfirmScreen(title, lines, "elect", func(screen Screen) error {
+		if err := ts.cluster.PreferredReplicaElection(elections); err != nil {
+			return err
+		}
+		ts.selected = make(map[int32]bool)
+		screen.Replace(NewElectionScreen(ts.cluster, elections))
+		return nil
+	}))
+	return nil
+}
+
+// addPartitions asks for the new number of partitions of the topic, previews
+// the replicas of the new partitions and adds them after confirmation. The
+// new replicas continue the layout of the existing ones, the way kafka-topics
+// does
+func (ts *TopicPartitionScreen) addPartitions(screen Screen) error {
+	assignment, err := ts.cluster.Topic(ts.topic)
+	if err != nil {
+		return fmt.Errorf("failed to read the assignment of topic %s: %v", ts.topic, err)
+	}
+	current := len(assignment.Replicas)
+	existing, ok := assignment.Replicas[0]
+	if !ok || len(existing) == 0 {
+		return fmt.Errorf("topic %s has no replicas for partition 0", ts.topic)
+	}
+
+	brokers := []int32{}
+	for _, b := range ts.cluster.Brokers() {
+		brokers = append(brokers, b.ID)
 	}
+	startIndex := continueIndex(brokers, existing[0])
+
+	title := "Add partitions to " + ts.topic
+	lines := []string{
+		fmt.Sprintf("Topic %s has %d partitions with %d replicas each.", ts.topic, current, len(existing)),
+		"Live brokers: " + formatBrokerIDs(brokers),
+	}
+	prompt := fmt.Sprintf("new number of partitions, more than %d: ", current)
+
+	screen.Push(NewPromptScreen(title, lines, prompt, func(screen Screen, input string) error {
+		partitions, err := strconv.Atoi(strings.TrimSpace(input))
+		if err != nil || partitions <= current {
+			return fmt.Errorf("the new number of partitions must be more than %d", current)
+		}
+
+		added, err := assignReplicas(brokers, int32(current), partitions-current, len(existing), startIndex, startIndex)
+		if err != nil {
+			return err
+		}
+
+		preview := []string{
+			fmt.Sprintf("Topic %s: %d -> %d partitions", ts.topic, current, partitions),
+			"",
+			fmt.Sprintf("WARNING: producers partitioning by key, such as with HashPartitioner, will send about %.0f%% of the keys", keysRemapped(current, partitions)*100),
+			"to another partition. Messages with the same key are no longer ordered across the change.",
+			"",
+			"Replicas of the new partitions:",
+		}
+		preview = append(preview, assignmentLines(added)...)
+
+		screen.Replace(NewConfirmScreen(title, preview, "add", func(screen Screen) error {
+			if err := ts.cluster.AddPartitions(ts.topic, assignment, added); err != nil {
+				return err
+			}
+
+			screen.Pop()
+			screen.SetStatus(fmt.Sprintf("%d partitions added to topic %s", partitions-current, ts.topic))
+			screen.Flush()
+			return nil
+		}))
+		return nil
+	}))
+	return nil
 }
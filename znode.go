@@ -0,0 +1,134 @@
+package ktop
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/samuel/go-zookeeper/zk"
+)
+
+// Znode is a ZooKeeper node with its data, stat, ACLs and children
+type Znode struct {
+	Path     string
+	Data     []byte
+	Stat     zk.Stat
+	ACL      []zk.ACL
+	Children []string
+}
+
+// Root returns the path of the cluster in ZooKeeper, its chroot or "/"
+func (c *Cluster) Root() string {
+	if c.keyBuilder.Chroot == "" {
+		return "/"
+	}
+	return c.keyBuilder.Chroot
+}
+
+// Znode reads a znode of the cluster
+func (c *Cluster) Znode(path string) (Znode, error) {
+	if !c.HasZookeeper() {
+		return Znode{}, ErrNoZookeeper
+	}
+
+	data, stat, err := c.zkconn.Get(path)
+	if err != nil {
+		return Znode{}, fmt.Errorf("failed to read %s: %v", path, err)
+	}
+
+	node := Znode{Path: path, Data: data, Stat: *stat}
+
+	if node.ACL, _, err = c.zkconn.GetACL(path); err != nil {
+		return Znode{}, fmt.Errorf("failed to read the ACLs of %s: %v", path, err)
+	}
+
+	if node.Children, _, err = c.zkconn.Children(path); err != nil {
+		return Znode{}, fmt.Errorf("failed to list %s: %v", path, err)
+	}
+	sort.Strings(node.Children)
+
+	return node, nil
+}
+
+// Child returns the path of a child of the znode
+func (n Znode) Child(name string) string {
+	if n.Path == "/" {
+		return "/" + name
+	}
+	return n.Path + "/" + name
+}
+
+// StatLines formats the stat of the znode
+func (n Znode) StatLines() []string {
+	owner := "none"
+	if n.Stat.EphemeralOwner != 0 {
+		owner = fmt.Sprintf("session 0x%x", n.Stat.EphemeralOwner)
+	}
+
+	return []string{
+		"ctime:           " + msToTime(n.Stat.Ctime).Format(time.RFC3339),
+		"mtime:           " + msToTime(n.Stat.Mtime).Format(time.RFC3339),
+		fmt.Sprintf("version:         %d", n.Stat.Version),
+		fmt.Sprintf("cversion:        %d", n.Stat.Cversion),
+		fmt.Sprintf("aversion:        %d", n.Stat.Aversion),
+		"ephemeral owner: " + owner,
+		fmt.Sprintf("data length:     %d", n.Stat.DataLength),
+		fmt.Sprintf("children:        %d", n.Stat.NumChildren),
+	}
+}
+
+// ACLLines formats the ACLs of the znode, with their permissions in the
+// letters of zkCli: cdrwa
+func (n Znode) ACLLines() []string {
+	lines := []string{}
+	for _, acl := range n.ACL {
+		perms := ""
+		for _, p := range []struct {
+			perm   int32
+			letter string
+		}{
+			{zk.PermCreate, "c"},
+			{zk.PermDelete, "d"},
+			{zk.PermRead, "r"},
+			{zk.PermWrite, "w"},
+			{zk.PermAdmin, "a"},
+		} {
+			if acl.Perms&p.perm != 0 {
+				perms += p.letter
+			}
+		}
+		lines = append(lines, fmt.Sprintf("%s:%s %s", acl.Scheme, acl.ID, perms))
+	}
+	return lines
+}
+
+// DataLines formats the data of the znode, pretty-printed when it is JSON
+func (n Znode) DataLines() []string {
+	if len(n.Data) == 0 {
+		return []string{"(empty)"}
+	}
+
+	var pretty bytes.Buffer
+	if err := json.Indent(&pretty, n.Data, "", "  "); err == nil {
+		return strings.Split(pretty.String(), "\n")
+	}
+
+	if !utf8.Valid(n.Data) {
+		return []string{fmt.Sprintf("(%d bytes of binary data)", len(n.Data))}
+	}
+	lines := strings.Split(strings.TrimRight(string(n.Data), "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = strings.Map(func(r rune) rune {
+			if unicode.IsPrint(r) {
+				return r
+			}
+			return '.'
+		}, line)
+	}
+	return lines
+}
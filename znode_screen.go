@@ -0,0 +1,150 @@
+package ktop
+
+import (
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/nsf/termbox-go"
+)
+
+// childrenWidth is the width of the children column of the znode browser
+const childrenWidth = 40
+
+// ZnodeScreen browses the ZooKeeper tree of the cluster. The children of the
+// znode are listed on the left, its stat, ACLs and data on the right. Opening
+// a child pushes another ZnodeScreen, so that going back goes up the tree
+type ZnodeScreen struct {
+	listCursor
+
+	path string
+	node Znode
+
+	// first line of the stat, ACLs and data shown, they scroll on their own
+	detail int
+
+	cluster *Cluster
+}
+
+func NewZnodeScreen(cluster *Cluster, path string) *ZnodeScreen {
+	return &ZnodeScreen{
+		cluster: cluster,
+		path:    path,
+	}
+}
+
+func (s *ZnodeScreen) WillShow(screen Screen) error {
+	node, err := s.cluster.Znode(s.path)
+	if err != nil {
+		// the znode may be gone, such as an ephemeral one
+		s.node = Znode{Path: s.path}
+		return err
+	}
+
+	s.node = node
+	s.clamp(len(s.node.Children))
+	return nil
+}
+
+// detailLines formats the stat, ACLs and data of the znode below each other
+func (s *ZnodeScreen) detailLines() []string {
+	lines := s.node.StatLines()
+	lines = append(lines, "", "ACLs:")
+	for _, acl := range s.node.ACLLines() {
+		lines = append(lines, "    "+acl)
+	}
+	lines = append(lines, "", "Data:")
+	return append(lines, s.node.DataLines()...)
+}
+
+func (s *ZnodeScreen) Refresh(screen Screen) {
+	termbox.Clear(coldef, coldef)
+	w, h = termbox.Size()
+
+	screen.Print(s.path, 0, 0, coldef, coldef)
+
+	// keep the stat, ACLs and data scrolled within their lines
+	lines := s.detailLines()
+	page := h - 4
+	if s.detail > len(lines)-page {
+		s.detail = len(lines) - page
+	}
+	if s.detail < 0 {
+		s.detail = 0
+	}
+
+	detailTitle := "ZNODE"
+	if len(lines) > page {
+		last := s.detail + page
+		if last > len(lines) {
+			last = len(lines)
+		}
+		detailTitle = fmt.Sprintf("ZNODE (lines %d-%d of %d, Ctrl-U/Ctrl-D to scroll)", s.detail+1, last, len(lines))
+	}
+	titles := fmt.Sprintf("     %-*s %s", childrenWidth, fmt.Sprintf("CHILDREN (%d)", len(s.node.Children)), detailTitle)
+	screen.Print(titles, 0, 2, coldef, coldef)
+
+	first, last := s.visible(len(s.node.Children), page)
+	for i := first; i < last; i++ {
+		name := s.node.Children[i]
+		if utf8.RuneCountInString(name) > childrenWidth {
+			name = string([]rune(name)[:childrenWidth-3]) + "..."
+		}
+		screen.Print(name, 5, i-s.Position+3, coldef, coldef)
+	}
+
+	if len(s.node.Children) > 0 {
+		screen.Print(" -> ", 0, s.Cursor-s.Position+3, coldef, coldef)
+	}
+
+	col := 5 + childrenWidth + 1
+	for i := s.detail; i < len(lines) && i < s.detail+page; i++ {
+		screen.Print(lines[i], col, i-s.detail+3, coldef, coldef)
+	}
+
+	termbox.HideCursor()
+	screen.Flush()
+}
+
+func (s *ZnodeScreen) OnKeyInput(screen Screen, keyEvent termbox.Event) {
+	_, h = termbox.Size()
+
+	switch keyEvent.Type {
+	case termbox.EventKey:
+		switch keyEvent.Key {
+		case termbox.KeyArrowLeft, termbox.KeyCtrlQ:
+			// go up
+			screen.Pop()
+
+		case termbox.KeyEnter, termbox.KeyArrowRight:
+			if len(s.node.Children) == 0 {
+				return
+			}
+			screen.Push(NewZnodeScreen(s.cluster, s.node.Child(s.node.Children[s.Cursor])))
+
+		case termbox.KeyCtrlD:
+			// scroll the stat, ACLs and data half a page
+			s.detail += (h - 4) / 2
+			s.Refresh(screen)
+
+		case termbox.KeyCtrlU:
+			s.detail -= (h - 4) / 2
+			s.Refresh(screen)
+
+		case termbox.KeyCtrlR:
+			if err := s.WillShow(screen); err != nil {
+				screen.SetError(err)
+			} else {
+				screen.SetStatus("")
+			}
+			s.Refresh(screen)
+
+		default:
+			if s.onKey(keyEvent.Key, len(s.node.Children), h-4) {
+				s.Refresh(screen)
+			}
+		}
+	case termbox.EventError:
+		screen.SetError(keyEvent.Err)
+		screen.Flush()
+	}
+}